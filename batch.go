@@ -0,0 +1,89 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"iter"
+	"os"
+	"regexp"
+)
+
+// The batch helpers walk a folder of player uploads laid out as one folder per clan
+// with turn reports named like "0900-04.0138.report.docx".
+//
+// They yield an error instead of stopping the program so that the caller can log
+// an unreadable folder and carry on with the rest of the batch. When a folder can't
+// be read, the iterator yields the error once with an empty name and stops.
+
+var (
+	// rxClanFolder matches the name of a clan folder, a 4-digit number starting with 0.
+	rxClanFolder = regexp.MustCompile(`^0\d\d\d$`)
+
+	// rxTurnReportFile captures the turn id, clan id, and extension from the name of a turn report.
+	// these look like:
+	// - 0900-04.0138.report.docx
+	// - 0900-04.0138.report.txt
+	rxTurnReportFile = regexp.MustCompile(`^(\d+-\d+)\.(\d{4})\.report\.(doc|docx|txt)$`)
+)
+
+// Clans returns an iterator over the names of the clan folders in the path.
+// The name of a clan folder must be a 4-digit number starting with 0.
+func Clans(path string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		list, err := os.ReadDir(path)
+		if err != nil {
+			yield("", err)
+			return
+		}
+		for _, item := range list {
+			if !item.IsDir() || !rxClanFolder.MatchString(item.Name()) {
+				continue
+			} else if !yield(item.Name(), nil) {
+				return
+			}
+		}
+	}
+}
+
+// TurnIds returns an iterator over the turn ids of the turn reports in the path.
+// Each turn id is yielded once, even if there are reports for it with different extensions.
+func TurnIds(path string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		list, err := os.ReadDir(path)
+		if err != nil {
+			yield("", err)
+			return
+		}
+		seen := map[string]bool{}
+		for _, item := range list {
+			match := rxTurnReportFile.FindStringSubmatch(item.Name())
+			if match == nil || item.IsDir() || seen[match[1]] {
+				continue
+			}
+			seen[match[1]] = true
+			if !yield(match[1], nil) {
+				return
+			}
+		}
+	}
+}
+
+// TurnReports returns an iterator over the names of the turn reports in the path
+// for the turn id with the extension, like "docx" or "txt".
+func TurnReports(path string, turnId, ext string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		list, err := os.ReadDir(path)
+		if err != nil {
+			yield("", err)
+			return
+		}
+		for _, item := range list {
+			match := rxTurnReportFile.FindStringSubmatch(item.Name())
+			if match == nil || item.IsDir() || match[1] != turnId || match[3] != ext {
+				continue
+			} else if !yield(item.Name(), nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"github.com/playbymail/tndocx"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBatchIterators(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"0138", "0250", "notes", "1138"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{
+		"0900-04.0138.report.docx", "0900-04.0138.report.txt",
+		"0900-05.0138.report.docx", "0900-05.0138.report.json", "readme.txt",
+	} {
+		if err := os.WriteFile(filepath.Join(root, "0138", name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	collect := func(seq func(func(string, error) bool)) (names []string, errs []error) {
+		for name, err := range seq {
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			names = append(names, name)
+		}
+		return names, errs
+	}
+
+	if got, errs := collect(tndocx.Clans(root)); errs != nil || !reflect.DeepEqual(got, []string{"0138", "0250"}) {
+		t.Errorf("Clans() = %q, %v, want [0138 0250]", got, errs)
+	}
+	if got, errs := collect(tndocx.TurnIds(filepath.Join(root, "0138"))); errs != nil || !reflect.DeepEqual(got, []string{"0900-04", "0900-05"}) {
+		t.Errorf("TurnIds() = %q, %v, want [0900-04 0900-05]", got, errs)
+	}
+	if got, errs := collect(tndocx.TurnReports(filepath.Join(root, "0138"), "0900-04", "txt")); errs != nil || !reflect.DeepEqual(got, []string{"0900-04.0138.report.txt"}) {
+		t.Errorf("TurnReports() = %q, %v, want [0900-04.0138.report.txt]", got, errs)
+	}
+
+	// a folder that can't be read yields an error instead of stopping the program
+	missing := filepath.Join(root, "0250", "docx")
+	for name, seq := range map[string]func(func(string, error) bool){
+		"Clans":       tndocx.Clans(missing),
+		"TurnIds":     tndocx.TurnIds(missing),
+		"TurnReports": tndocx.TurnReports(missing, "0900-04", "docx"),
+	} {
+		if got, errs := collect(seq); got != nil || len(errs) != 1 || !os.IsNotExist(errs[0]) {
+			t.Errorf("%s() = %q, %v, want one not-exist error", name, got, errs)
+		}
+	}
+}
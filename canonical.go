@@ -0,0 +1,78 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Canonicalize returns the cleaned text of a report: lower case, with spaces
+// compressed, only the lines needed for mapping, and the movement lines pre-processed.
+// It uses a Parser with the default configuration.
+func Canonicalize(input []byte) []byte {
+	p, _ := NewParser()
+	return p.Canonicalize(input)
+}
+
+// Canonicalize returns the cleaned text of a report: lower case, with spaces
+// compressed, only the lines needed for mapping, and the movement lines pre-processed.
+// Units are written in the order they appear in the input unless the parser was
+// created with WithSortedUnits.
+func (p *Parser) Canonicalize(input []byte) []byte {
+	lines := bytes.Split(CompressSpaces(bytes.ToLower(input)), []byte{'\n'})
+	lines = RemoveNonMappingLines(lines)
+	for i := range lines {
+		lines[i] = PreProcessMovementLine(lines[i])
+	}
+	if p.sortUnits {
+		lines = sortUnitBlocks(lines)
+	}
+	return bytes.Join(lines, []byte{'\n'})
+}
+
+// WithSortedUnits makes Canonicalize write the units in order of their id rather than
+// the order they appear in the report. The same unit then lands on roughly the same
+// line in the text for every turn, which makes the texts of two turns easier to diff.
+func WithSortedUnits() Option {
+	return func(p *Parser) error {
+		p.sortUnits = true
+		return nil
+	}
+}
+
+// sortUnitBlocks returns the lines with each unit's block sorted by unit id.
+// A block is a unit header and the lines up to the next header. Lines before the
+// first header stay at the top. Headers without a unit id are sorted by their text,
+// and blocks with the same key keep their order.
+func sortUnitBlocks(lines [][]byte) [][]byte {
+	type block struct {
+		key   string
+		lines [][]byte
+	}
+	var preamble [][]byte
+	var blocks []*block
+	for _, line := range lines {
+		if IsUnitHeader(line) {
+			key := string(line)
+			if match := rxHeaderUnitId.FindSubmatch(line); match != nil {
+				key = string(match[2])
+			}
+			blocks = append(blocks, &block{key: key})
+		}
+		if len(blocks) == 0 {
+			preamble = append(preamble, line)
+		} else {
+			blocks[len(blocks)-1].lines = append(blocks[len(blocks)-1].lines, line)
+		}
+	}
+	sort.SliceStable(blocks, func(i, j int) bool {
+		return blocks[i].key < blocks[j].key
+	})
+	sorted := make([][]byte, 0, len(lines))
+	sorted = append(sorted, preamble...)
+	for _, b := range blocks {
+		sorted = append(sorted, b.lines...)
+	}
+	return sorted
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"github.com/playbymail/tndocx"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalizeSortedUnits(t *testing.T) {
+	turn4 := strings.Join([]string{
+		"Current Turn 900-04 (#4), Summer, FINE",
+		"Element 0138e1, , Current Hex = QQ 0710, (Previous Hex = QQ 0709)",
+		"0138e1 Status: PRAIRIE, 0138e1",
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		"Tribe Movement: Move N-PR",
+		"0138 Status: PRAIRIE, 0138",
+		"Courier 0138c1, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+	}, "\n")
+	turn5 := strings.Join([]string{
+		"Current Turn 900-05 (#5), Summer, FINE",
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		"0138 Status: PRAIRIE, 0138",
+		"Courier 0138c1, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		"Element 0138e1, , Current Hex = QQ 0710, (Previous Hex = QQ 0710)",
+		"0138e1 Status: PRAIRIE, 0138e1",
+	}, "\n")
+
+	p, err := tndocx.NewParser(tndocx.WithSortedUnits())
+	if err != nil {
+		t.Fatalf("NewParser() error = %v", err)
+	}
+	for _, tt := range []struct {
+		name, input, want string
+	}{
+		{name: "turn 4", input: turn4, want: strings.Join([]string{
+			"current turn 900-04(#4),summer,fine",
+			"tribe 0138,current hex = qq 0709,(previous hex = qq 0709)",
+			"tribe movement:move n-pr",
+			"0138 status:prairie,0138",
+			"courier 0138c1,current hex = qq 0709,(previous hex = qq 0709)",
+			"element 0138e1,current hex = qq 0710,(previous hex = qq 0709)",
+			"0138e1 status:prairie,0138e1",
+		}, "\n")},
+		{name: "turn 5", input: turn5, want: strings.Join([]string{
+			"current turn 900-05(#5),summer,fine",
+			"tribe 0138,current hex = qq 0709,(previous hex = qq 0709)",
+			"0138 status:prairie,0138",
+			"courier 0138c1,current hex = qq 0709,(previous hex = qq 0709)",
+			"element 0138e1,current hex = qq 0710,(previous hex = qq 0710)",
+			"0138e1 status:prairie,0138e1",
+		}, "\n")},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(p.Canonicalize([]byte(tt.input))); got != tt.want {
+				t.Errorf("Canonicalize() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	// without the option, the units stay in the order of the report
+	got := strings.Split(string(tndocx.Canonicalize([]byte(turn4))), "\n")
+	if len(got) != 7 || !strings.HasPrefix(got[1], "element 0138e1,") {
+		t.Errorf("Canonicalize() = %q, want units in source order", got)
+	}
+}
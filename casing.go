@@ -0,0 +1,77 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"bytes"
+	"strings"
+)
+
+// WithPreserveCase keeps the original capitalization of unit names and settlement
+// names, like "Dowdy Holler". The rest of the report is still matched without
+// regard to case. It applies to ToReport, ParseText, and Parse with text or Word input.
+func WithPreserveCase() Option {
+	return func(p *Parser) error {
+		p.preserveCase = true
+		return nil
+	}
+}
+
+// nameCasing maps the lower case spelling of the names in a report to their original spelling.
+// The parser works on lower case text, so the names it captures are looked up here afterward.
+type nameCasing map[string]string
+
+// addLines records the spelling of the names in the lines. The lines must have their
+// spaces compressed the same way as the text the parser matched against.
+// Names are the segments between commas and backslashes, without any quotes.
+// When a name is spelled more than one way, the first spelling is kept.
+func (c nameCasing) addLines(lines ...[]byte) {
+	for _, line := range lines {
+		for _, step := range strings.Split(string(line), "\\") {
+			for _, segment := range splitOutsideParens(step) {
+				name := unquote(strings.TrimSpace(segment))
+				key := strings.ToLower(name)
+				if _, ok := c[key]; !ok && key != name {
+					c[key] = name
+					// settlements get their spaces back, so they are looked up that way too
+					c[settlementName(key)] = settlementName(name)
+				}
+			}
+		}
+	}
+}
+
+// original returns the original spelling of a name captured from lower case text.
+func (c nameCasing) original(name string) string {
+	if spelling, ok := c[name]; ok {
+		return spelling
+	}
+	return name
+}
+
+// restoreUnit puts the original spelling back into the names captured for the unit.
+func (c nameCasing) restoreUnit(unit *Unit) {
+	unit.Name = c.original(unit.Name)
+	if unit.Status != nil {
+		unit.Status.Settlement = c.original(unit.Status.Settlement)
+	}
+	for _, step := range unit.Moves {
+		step.Settlement = c.original(step.Settlement)
+	}
+}
+
+// restore puts the original spelling back into the names captured for every unit in the report.
+func (c nameCasing) restore(report *Report) {
+	for _, unit := range report.Units {
+		c.restoreUnit(unit)
+	}
+}
+
+// lowerLines returns lower case copies of the lines.
+func lowerLines(lines [][]byte) [][]byte {
+	lowered := make([][]byte, len(lines))
+	for n, line := range lines {
+		lowered[n] = bytes.ToLower(line)
+	}
+	return lowered
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"github.com/playbymail/tndocx"
+	"strings"
+	"testing"
+)
+
+func TestParserPreserveCase(t *testing.T) {
+	input := strings.Join([]string{
+		"Tribe 0138, Dowdy Holler, Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"Current Turn 900-04 (#4), Summer, FINE",
+		`Tribe Movement: Move N-PR, "Big Stone"\NE-GH`,
+		"0138 Status: PRAIRIE, West Harbor, O NE, 0138",
+		"Element 0138e1, , Current Hex = QQ 0705, (Previous Hex = QQ 0709)",
+		"Tribe Goes to QQ 0705, McAllister's Ford",
+		"",
+	}, "\n")
+	p, err := tndocx.NewParser(tndocx.WithPreserveCase())
+	if err != nil {
+		t.Fatalf("NewParser() error = %v", err)
+	}
+
+	check := func(t *testing.T, r *tndocx.Report) {
+		tribe, element := r.Units["0138"], r.Units["0138e1"]
+		if tribe == nil || element == nil {
+			t.Fatalf("Units = %+v, want 0138 and 0138e1", r.Units)
+		}
+		if tribe.Name != "Dowdy Holler" {
+			t.Errorf("Name = %q, want %q", tribe.Name, "Dowdy Holler")
+		}
+		if tribe.Status == nil || tribe.Status.Settlement != "West Harbor" || tribe.Status.Terrain != "prairie" {
+			t.Errorf("Status = %+v, want West Harbor on prairie", tribe.Status)
+		}
+		if len(tribe.Moves) != 2 || tribe.Moves[0].Settlement != "Big Stone" {
+			t.Errorf("Moves = %+v, want Big Stone on the first move", tribe.Moves)
+		}
+		if len(element.Moves) != 1 || element.Moves[0].Settlement != "McAllister's Ford" {
+			t.Errorf("Moves = %+v, want McAllister's Ford", element.Moves)
+		}
+	}
+
+	t.Run("ToReport", func(t *testing.T) {
+		var lines [][]byte
+		for _, line := range strings.Split(input, "\n") {
+			lines = append(lines, tndocx.CompressSpaces([]byte(line)))
+		}
+		check(t, p.ToReport("test", lines))
+	})
+	t.Run("Parse", func(t *testing.T) {
+		r, err := p.Parse("test", []byte(input))
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		check(t, r)
+	})
+	t.Run("ParseText", func(t *testing.T) {
+		sections, err := p.ParseText([]byte(input))
+		if err != nil {
+			t.Fatalf("ParseText() error = %v", err)
+		}
+		r, err := p.ParseReport("test", sections)
+		if err != nil {
+			t.Fatalf("ParseReport() error = %v", err)
+		}
+		check(t, r)
+	})
+
+	t.Run("Parse docx", func(t *testing.T) {
+		body := &bytes.Buffer{}
+		for _, line := range strings.Split(input, "\n") {
+			body.WriteString("<w:p><w:r><w:t>")
+			_ = xml.EscapeText(body, []byte(line))
+			body.WriteString("</w:t></w:r></w:p>")
+		}
+		buf := &bytes.Buffer{}
+		zw := zip.NewWriter(buf)
+		if w, err := zw.Create("word/document.xml"); err != nil {
+			t.Fatalf("create: %v", err)
+		} else if _, err = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><w:document><w:body>` + body.String() + `</w:body></w:document>`)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("close: %v", err)
+		}
+		r, err := p.Parse("test.docx", buf.Bytes())
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		check(t, r)
+	})
+
+	// without the option, names are in lower case
+	p, _ = tndocx.NewParser()
+	r, err := p.Parse("test", []byte(input))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	} else if name := r.Units["0138"].Name; name != "dowdy holler" {
+		t.Errorf("Name = %q, want %q", name, "dowdy holler")
+	}
+}
+
+func TestParserPreserveCaseSettlementDetails(t *testing.T) {
+	p, err := tndocx.NewParser(tndocx.WithPreserveCase())
+	if err != nil {
+		t.Fatalf("NewParser() error = %v", err)
+	}
+	r := p.ToReport("test", [][]byte{
+		[]byte("tribe 0138,,current hex = ## 0709,(previous hex = ## 0709)"),
+		tndocx.CompressSpaces([]byte("0138 status:prairie,Arcadia (Port, Market),0138")),
+	})
+	if got, want := r.Units["0138"].Status.Settlement, "Arcadia (Port, Market)"; got != want {
+		t.Errorf("Settlement = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,402 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package docx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"unicode/utf16"
+)
+
+var (
+	ErrEncryptedDoc = errors.New("encrypted word document")
+	ErrInvalidDoc   = errors.New("invalid word document")
+)
+
+// ReadDoc extracts the text from a legacy Word 97-2003 document, converts it to
+// lower-case plain text, and returns the text as a byte slice.
+//
+// A .doc file is a compound file (a small file system inside the file) holding a
+// WordDocument stream and a table stream. The table stream has a piece table that
+// lists where each run of the document's text is stored in the WordDocument stream.
+// Only the main document text is read; footnotes, headers, and comments are skipped,
+// as are the instructions for fields like hyperlinks (the field's result is kept).
+// Formatting, including tables, is lost.
+func ReadDoc(input []byte) ([]byte, error) {
+	return readDoc(input, true)
+}
+
+// ReadDocOriginalCase is ReadDoc without the conversion to lower case.
+func ReadDocOriginalCase(input []byte) ([]byte, error) {
+	return readDoc(input, false)
+}
+
+// readDoc extracts the text from a legacy Word document, converting it to lower case if lower is set.
+func readDoc(input []byte, lower bool) ([]byte, error) {
+	if DetectWordDocType(input) != Doc {
+		return nil, ErrInvalidDoc
+	}
+	cf, err := openCompoundFile(input)
+	if err != nil {
+		return nil, err
+	}
+	wordDocument, err := cf.stream("WordDocument")
+	if err != nil {
+		return nil, err
+	}
+
+	// the file information block (fib) at the start of the WordDocument stream
+	// tells us which table stream to use, how much text there is, and where the
+	// piece table is stored.
+	if len(wordDocument) < 34 || le16(wordDocument, 0) != 0xA5EC {
+		return nil, ErrInvalidDoc
+	}
+	flags := le16(wordDocument, 0x0A)
+	if flags&0x0100 != 0 {
+		return nil, ErrEncryptedDoc
+	}
+	tableName := "0Table"
+	if flags&0x0200 != 0 {
+		tableName = "1Table"
+	}
+	// the fib has variable length arrays of shorts, longs, and offset/length pairs.
+	// ccpText is the fourth long and the piece table (clx) is the 34th pair.
+	offset := 32
+	offset += 2 + 2*int(le16(wordDocument, offset))
+	if offset+2+4*4 > len(wordDocument) {
+		return nil, ErrInvalidDoc
+	}
+	ccpText := le32(wordDocument, offset+2+3*4)
+	offset += 2 + 4*int(le16(wordDocument, offset))
+	if offset+2 > len(wordDocument) || le16(wordDocument, offset) < 34 || offset+2+34*8 > len(wordDocument) {
+		return nil, ErrInvalidDoc
+	}
+	fcClx, lcbClx := le32(wordDocument, offset+2+33*8), le32(wordDocument, offset+2+33*8+4)
+
+	table, err := cf.stream(tableName)
+	if err != nil {
+		return nil, err
+	} else if uint64(fcClx)+uint64(lcbClx) > uint64(len(table)) {
+		return nil, ErrInvalidDoc
+	}
+	pieces, err := pieceTable(table[fcClx : fcClx+lcbClx])
+	if err != nil {
+		return nil, err
+	}
+
+	text := &docText{}
+	for _, pc := range pieces {
+		if ccpText == 0 {
+			break
+		}
+		count := pc.count
+		if count > ccpText {
+			count = ccpText
+		}
+		ccpText -= count
+		if pc.compressed {
+			if uint64(pc.offset)+uint64(count) > uint64(len(wordDocument)) {
+				return nil, ErrInvalidDoc
+			}
+			for _, ch := range wordDocument[pc.offset : pc.offset+count] {
+				// compressed text is 8-bit; anything outside ascii is scrubbed later anyway
+				text.add(rune(ch))
+			}
+		} else {
+			if uint64(pc.offset)+2*uint64(count) > uint64(len(wordDocument)) {
+				return nil, ErrInvalidDoc
+			}
+			units := make([]uint16, count)
+			for n := range units {
+				units[n] = le16(wordDocument, int(pc.offset)+2*n)
+			}
+			for _, ch := range utf16.Decode(units) {
+				text.add(ch)
+			}
+		}
+	}
+	if lower {
+		return scrubNonPrintingGlyphs([]byte(strings.ToLower(text.buf.String()))), nil
+	}
+	return scrubNonPrintingGlyphs(text.buf.Bytes()), nil
+}
+
+// docText collects the text of a document, removing field instructions and
+// turning the paragraph and line marks into new lines.
+type docText struct {
+	buf bytes.Buffer
+	// fields is a stack with an entry for each open field. The entry is true
+	// while we're reading the field's instructions rather than its result.
+	fields []bool
+}
+
+func (t *docText) add(ch rune) {
+	switch ch {
+	case 0x13: // start of a field
+		t.fields = append(t.fields, true)
+		return
+	case 0x14: // end of the field's instructions, start of its result
+		if len(t.fields) != 0 {
+			t.fields[len(t.fields)-1] = false
+		}
+		return
+	case 0x15: // end of a field
+		if len(t.fields) != 0 {
+			t.fields = t.fields[:len(t.fields)-1]
+		}
+		return
+	}
+	for _, instructions := range t.fields {
+		if instructions {
+			return
+		}
+	}
+	switch {
+	case ch == '\r', ch == 0x0B, ch == 0x07: // paragraph mark, line break, table cell mark
+		t.buf.WriteByte('\n')
+	case ch == '\t':
+		t.buf.WriteByte(' ')
+	case ch < 0x20:
+		// other control characters mark pictures, footnotes, and page breaks
+	default:
+		t.buf.WriteRune(ch)
+	}
+}
+
+// piece is a run of the document's text in the WordDocument stream.
+type piece struct {
+	offset     uint32 // offset of the text in the WordDocument stream
+	count      uint32 // number of characters in the piece
+	compressed bool   // true if the text is stored as 8-bit characters rather than utf-16
+}
+
+// pieceTable returns the pieces from the clx structure in the table stream.
+// The clx starts with optional formatting (prc) entries, which we skip,
+// followed by the piece table (pcdt).
+func pieceTable(clx []byte) ([]piece, error) {
+	for len(clx) != 0 && clx[0] == 0x01 {
+		if len(clx) < 3 {
+			return nil, ErrInvalidDoc
+		}
+		size := 3 + int(le16(clx, 1))
+		if size > len(clx) {
+			return nil, ErrInvalidDoc
+		}
+		clx = clx[size:]
+	}
+	if len(clx) < 5 || clx[0] != 0x02 {
+		return nil, ErrInvalidDoc
+	}
+	lcb := le32(clx, 1)
+	if uint64(lcb) > uint64(len(clx)-5) || lcb < 4 || (lcb-4)%12 != 0 {
+		return nil, ErrInvalidDoc
+	}
+	plc := clx[5 : 5+lcb]
+	// the plc is n+1 character positions followed by n 8-byte piece descriptors
+	n := (len(plc) - 4) / 12
+	pieces := make([]piece, 0, n)
+	for i := 0; i < n; i++ {
+		start, end := le32(plc, 4*i), le32(plc, 4*(i+1))
+		if end < start {
+			return nil, ErrInvalidDoc
+		}
+		fc := le32(plc, 4*(n+1)+8*i+2)
+		pc := piece{offset: fc, count: end - start}
+		if fc&0x40000000 != 0 {
+			pc.offset, pc.compressed = (fc&^0x40000000)/2, true
+		}
+		pieces = append(pieces, pc)
+	}
+	return pieces, nil
+}
+
+// compoundFile is a reader for the compound file format used by legacy Office documents.
+// The file is divided into sectors. A file allocation table (fat) chains the sectors of
+// each stream together, and a directory lists the streams. Streams smaller than the
+// cutoff are stored in 64-byte mini sectors inside the root entry's stream instead.
+type compoundFile struct {
+	data       []byte
+	sectorSize int
+	miniSize   int
+	cutoff     uint32
+	fat        []uint32
+	miniFat    []uint32
+	miniStream []byte
+	entries    []dirEntry
+}
+
+// dirEntry is an entry in the compound file's directory.
+type dirEntry struct {
+	name  string
+	kind  byte // 1 for a storage, 2 for a stream, 5 for the root
+	start uint32
+	size  uint32
+}
+
+const (
+	// sector numbers with special meanings in the fat
+	freeSector = 0xFFFFFFFF
+	endOfChain = 0xFFFFFFFE
+
+	headerSize   = 512
+	dirEntrySize = 128
+)
+
+// openCompoundFile reads the header, allocation tables, and directory of a compound file.
+func openCompoundFile(data []byte) (*compoundFile, error) {
+	if len(data) < headerSize {
+		return nil, ErrInvalidDoc
+	}
+	sectorShift, miniShift := le16(data, 0x1E), le16(data, 0x20)
+	if sectorShift != 9 && sectorShift != 12 || miniShift != 6 {
+		return nil, ErrInvalidDoc
+	}
+	cf := &compoundFile{
+		data:       data,
+		sectorSize: 1 << sectorShift,
+		miniSize:   1 << miniShift,
+		cutoff:     le32(data, 0x38),
+	}
+
+	// the header holds the first 109 entries of the list of fat sectors (the difat).
+	// any more are stored in a chain of difat sectors, where the last entry is the next sector.
+	var fatSectors []uint32
+	for i := 0; i < 109; i++ {
+		fatSectors = append(fatSectors, le32(data, 0x4C+4*i))
+	}
+	// the count comes from the file, so it is capped at the number of sectors in the file,
+	// and a chain that visits a sector twice must loop.
+	perSector := cf.sectorSize/4 - 1
+	visited := map[uint32]bool{}
+	for next, n := le32(data, 0x44), min(int(le32(data, 0x48)), len(data)/cf.sectorSize); next != endOfChain && next != freeSector && n > 0; n-- {
+		if visited[next] {
+			return nil, ErrInvalidDoc
+		}
+		visited[next] = true
+		sector, ok := cf.sector(next)
+		if !ok {
+			return nil, ErrInvalidDoc
+		}
+		for i := 0; i < perSector; i++ {
+			fatSectors = append(fatSectors, le32(sector, 4*i))
+		}
+		next = le32(sector, 4*perSector)
+	}
+	for _, sn := range fatSectors[:min(len(fatSectors), int(le32(data, 0x2C)))] {
+		sector, ok := cf.sector(sn)
+		if !ok {
+			return nil, ErrInvalidDoc
+		}
+		for i := 0; i < cf.sectorSize/4; i++ {
+			cf.fat = append(cf.fat, le32(sector, 4*i))
+		}
+	}
+
+	dir, err := cf.chain(cf.fat, le32(data, 0x30), cf.sector)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i+dirEntrySize <= len(dir); i += dirEntrySize {
+		entry := dir[i : i+dirEntrySize]
+		nameLength := int(le16(entry, 0x40))
+		if nameLength < 2 || nameLength > 64 {
+			continue
+		}
+		name := make([]uint16, nameLength/2-1)
+		for n := range name {
+			name[n] = le16(entry, 2*n)
+		}
+		cf.entries = append(cf.entries, dirEntry{
+			name:  string(utf16.Decode(name)),
+			kind:  entry[0x42],
+			start: le32(entry, 0x74),
+			size:  le32(entry, 0x78),
+		})
+	}
+
+	miniFat, err := cf.chain(cf.fat, le32(data, 0x3C), cf.sector)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i+4 <= len(miniFat); i += 4 {
+		cf.miniFat = append(cf.miniFat, le32(miniFat, i))
+	}
+	for _, entry := range cf.entries {
+		if entry.kind == 5 {
+			if cf.miniStream, err = cf.chain(cf.fat, entry.start, cf.sector); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return cf, nil
+}
+
+// stream returns the contents of the named stream.
+func (cf *compoundFile) stream(name string) ([]byte, error) {
+	for _, entry := range cf.entries {
+		if entry.kind != 2 || !strings.EqualFold(entry.name, name) {
+			continue
+		}
+		var data []byte
+		var err error
+		if entry.size < cf.cutoff {
+			data, err = cf.chain(cf.miniFat, entry.start, cf.miniSector)
+		} else {
+			data, err = cf.chain(cf.fat, entry.start, cf.sector)
+		}
+		if err != nil {
+			return nil, err
+		} else if uint64(entry.size) > uint64(len(data)) {
+			return nil, ErrInvalidDoc
+		}
+		return data[:entry.size], nil
+	}
+	return nil, errors.New(name + " stream not found")
+}
+
+// chain returns the contents of the sectors chained together in the allocation table.
+func (cf *compoundFile) chain(table []uint32, start uint32, sector func(uint32) ([]byte, bool)) ([]byte, error) {
+	var data []byte
+	// a chain that visits a sector twice must loop, like the difat chain in openCompoundFile
+	visited := map[uint32]bool{}
+	for sn := start; sn != endOfChain && sn != freeSector; sn = table[sn] {
+		if int(sn) >= len(table) || visited[sn] {
+			return nil, ErrInvalidDoc
+		}
+		visited[sn] = true
+		b, ok := sector(sn)
+		if !ok {
+			return nil, ErrInvalidDoc
+		}
+		data = append(data, b...)
+	}
+	return data, nil
+}
+
+// sector returns the contents of a sector. The header takes the place of sector -1.
+func (cf *compoundFile) sector(sn uint32) ([]byte, bool) {
+	offset := (uint64(sn) + 1) * uint64(cf.sectorSize)
+	if offset+uint64(cf.sectorSize) > uint64(len(cf.data)) {
+		return nil, false
+	}
+	return cf.data[offset : offset+uint64(cf.sectorSize)], true
+}
+
+// miniSector returns the contents of a sector in the mini stream.
+func (cf *compoundFile) miniSector(sn uint32) ([]byte, bool) {
+	offset := uint64(sn) * uint64(cf.miniSize)
+	if offset+uint64(cf.miniSize) > uint64(len(cf.miniStream)) {
+		return nil, false
+	}
+	return cf.miniStream[offset : offset+uint64(cf.miniSize)], true
+}
+
+func le16(b []byte, offset int) uint16 {
+	return binary.LittleEndian.Uint16(b[offset:])
+}
+
+func le32(b []byte, offset int) uint32 {
+	return binary.LittleEndian.Uint32(b[offset:])
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package docx_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/playbymail/tndocx/docx"
+	"testing"
+	"unicode/utf16"
+)
+
+// docPiece is a run of text in a legacy Word document.
+type docPiece struct {
+	text       string
+	compressed bool
+}
+
+// newDoc returns a minimal legacy Word document with the pieces of text.
+// Only the first ccpText characters are part of the main document.
+//
+// The compound file has 512-byte sectors: the fat, the directory, the mini fat,
+// eight sectors for the WordDocument stream, and then the mini stream, which
+// holds the small 1Table stream.
+func newDoc(t *testing.T, pieces []docPiece, ccpText int, flags uint16) []byte {
+	t.Helper()
+	le := binary.LittleEndian
+	const sectorSize, textOffset = 512, 1024
+
+	// the WordDocument stream starts with the fib, followed by the text
+	wordDocument := make([]byte, 4096)
+	le.PutUint16(wordDocument[0:], 0xA5EC)
+	le.PutUint16(wordDocument[0x0A:], flags|0x0200) // use the 1Table stream
+	le.PutUint16(wordDocument[32:], 14)             // csw
+	le.PutUint16(wordDocument[62:], 22)             // cslw
+	le.PutUint32(wordDocument[76:], uint32(ccpText))
+	le.PutUint16(wordDocument[152:], 93) // cbRgFcLcb
+
+	var cps, fcs []uint32
+	cp, offset := 0, textOffset
+	for _, pc := range pieces {
+		cps = append(cps, uint32(cp))
+		if pc.compressed {
+			fcs = append(fcs, uint32(offset*2)|0x40000000)
+			offset += copy(wordDocument[offset:], pc.text)
+			cp += len(pc.text)
+		} else {
+			fcs = append(fcs, uint32(offset))
+			units := utf16.Encode([]rune(pc.text))
+			for _, u := range units {
+				le.PutUint16(wordDocument[offset:], u)
+				offset += 2
+			}
+			cp += len(units)
+		}
+	}
+	cps = append(cps, uint32(cp))
+
+	// the 1Table stream holds only the piece table
+	table := []byte{0x02, 0, 0, 0, 0}
+	for _, cp := range cps {
+		table = le.AppendUint32(table, cp)
+	}
+	for _, fc := range fcs {
+		table = le.AppendUint16(table, 0)
+		table = le.AppendUint32(table, fc)
+		table = le.AppendUint16(table, 0)
+	}
+	le.PutUint32(table[1:], uint32(len(table)-5))
+	le.PutUint32(wordDocument[154+33*8+4:], uint32(len(table))) // lcbClx; fcClx is zero
+	miniSectors := (len(table) + 63) / 64
+	miniStream := make([]byte, ((miniSectors*64+sectorSize-1)/sectorSize)*sectorSize)
+	copy(miniStream, table)
+
+	sectors := 3 + len(wordDocument)/sectorSize + len(miniStream)/sectorSize
+	data := make([]byte, (sectors+1)*sectorSize)
+	sector := func(n int) []byte {
+		return data[(n+1)*sectorSize : (n+2)*sectorSize]
+	}
+
+	header := data[:sectorSize]
+	copy(header, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1})
+	le.PutUint16(header[0x18:], 0x3E)
+	le.PutUint16(header[0x1A:], 3)
+	le.PutUint16(header[0x1C:], 0xFFFE)
+	le.PutUint16(header[0x1E:], 9)
+	le.PutUint16(header[0x20:], 6)
+	le.PutUint32(header[0x2C:], 1) // one fat sector
+	le.PutUint32(header[0x30:], 1) // directory
+	le.PutUint32(header[0x38:], 4096)
+	le.PutUint32(header[0x3C:], 2) // mini fat
+	le.PutUint32(header[0x40:], 1)
+	le.PutUint32(header[0x44:], 0xFFFFFFFE)
+	for i := 0; i < 109; i++ {
+		le.PutUint32(header[0x4C+4*i:], 0xFFFFFFFF)
+	}
+	le.PutUint32(header[0x4C:], 0)
+
+	chain := func(table []byte, start, count int) {
+		for n := start; n < start+count-1; n++ {
+			le.PutUint32(table[4*n:], uint32(n+1))
+		}
+		le.PutUint32(table[4*(start+count-1):], 0xFFFFFFFE)
+	}
+	fat, miniFat := sector(0), sector(2)
+	for i := 0; i < sectorSize; i += 4 {
+		le.PutUint32(fat[i:], 0xFFFFFFFF)
+		le.PutUint32(miniFat[i:], 0xFFFFFFFF)
+	}
+	le.PutUint32(fat[0:], 0xFFFFFFFD) // the fat sector itself
+	chain(fat, 1, 1)
+	chain(fat, 2, 1)
+	chain(fat, 3, len(wordDocument)/sectorSize)
+	rootStart := 3 + len(wordDocument)/sectorSize
+	chain(fat, rootStart, len(miniStream)/sectorSize)
+	chain(miniFat, 0, miniSectors)
+
+	dir := sector(1)
+	entry := func(n int, name string, kind byte, start, size int) {
+		e := dir[128*n : 128*(n+1)]
+		units := utf16.Encode([]rune(name))
+		for i, u := range units {
+			le.PutUint16(e[2*i:], u)
+		}
+		le.PutUint16(e[0x40:], uint16(2*len(units)+2))
+		e[0x42] = kind
+		le.PutUint32(e[0x74:], uint32(start))
+		le.PutUint32(e[0x78:], uint32(size))
+	}
+	entry(0, "Root Entry", 5, rootStart, len(miniStream))
+	entry(1, "WordDocument", 2, 3, len(wordDocument))
+	entry(2, "1Table", 2, 0, len(table))
+
+	for n := 0; n < len(wordDocument)/sectorSize; n++ {
+		copy(sector(3+n), wordDocument[n*sectorSize:])
+	}
+	for n := 0; n < len(miniStream)/sectorSize; n++ {
+		copy(sector(rootStart+n), miniStream[n*sectorSize:])
+	}
+	return data
+}
+
+func TestReadDoc(t *testing.T) {
+	pieces := []docPiece{
+		{text: "Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)\r", compressed: true},
+		{text: "Tribe Movement: Move \x13 HYPERLINK \"x\" \x14N-PR\x15\r0138 Status: PRAIRIE,\tO NE\r"},
+		{text: "a footnote\r", compressed: true},
+	}
+	ccpText := len(pieces[0].text) + len(pieces[1].text)
+	input := newDoc(t, pieces, ccpText, 0)
+
+	if got := docx.DetectWordDocType(input); got != docx.Doc {
+		t.Fatalf("DetectWordDocType() = %v, want %v", got, docx.Doc)
+	}
+	text, err := docx.ReadDoc(input)
+	if err != nil {
+		t.Fatalf("ReadDoc() error = %v", err)
+	}
+	want := "tribe 0138, , current hex = qq 0709, (previous hex = qq 0708)\ntribe movement: move n-pr\n0138 status: prairie, o ne\n"
+	if string(text) != want {
+		t.Errorf("ReadDoc() = %q, want %q", text, want)
+	}
+	text, err = docx.ReadDocOriginalCase(input)
+	if err != nil {
+		t.Fatalf("ReadDocOriginalCase() error = %v", err)
+	}
+	want = "Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)\nTribe Movement: Move N-PR\n0138 Status: PRAIRIE, O NE\n"
+	if string(text) != want {
+		t.Errorf("ReadDocOriginalCase() = %q, want %q", text, want)
+	}
+
+	if _, err := docx.ReadDoc(newDoc(t, pieces, ccpText, 0x0100)); !errors.Is(err, docx.ErrEncryptedDoc) {
+		t.Errorf("encrypted: ReadDoc() error = %v, want %v", err, docx.ErrEncryptedDoc)
+	}
+	// a docx is a zip archive, which must not be mistaken for a compound file
+	zipped := newDocx(t, map[string][]byte{"word/document.xml": []byte(`<w:document/>`)})
+	if got := docx.DetectWordDocType(zipped); got != docx.Docx {
+		t.Errorf("DetectWordDocType(docx) = %v, want %v", got, docx.Docx)
+	}
+	if _, err := docx.ReadDoc(zipped); !errors.Is(err, docx.ErrInvalidDoc) {
+		t.Errorf("docx: ReadDoc() error = %v, want %v", err, docx.ErrInvalidDoc)
+	}
+}
+
+func TestReadDocDifatLoop(t *testing.T) {
+	pieces := []docPiece{{text: "Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)\r", compressed: true}}
+	input := newDoc(t, pieces, len(pieces[0].text), 0)
+	// the last sector of the WordDocument stream is used as a difat sector that points
+	// to itself, with a count large enough to exhaust memory if the loop isn't caught.
+	const sectorSize, difatSector = 512, 10
+	binary.LittleEndian.PutUint32(input[0x44:], difatSector)
+	binary.LittleEndian.PutUint32(input[0x48:], 0xFFFFFFFF)
+	binary.LittleEndian.PutUint32(input[(difatSector+2)*sectorSize-4:], difatSector)
+	if _, err := docx.ReadDoc(input); !errors.Is(err, docx.ErrInvalidDoc) {
+		t.Errorf("ReadDoc() error = %v, want %v", err, docx.ErrInvalidDoc)
+	}
+}
+
+func TestReadDocChainLoop(t *testing.T) {
+	pieces := []docPiece{{text: "Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)\r", compressed: true}}
+	input := newDoc(t, pieces, len(pieces[0].text), 0)
+	// the fat entry for the last sector of the WordDocument stream points back to the sector itself
+	const sectorSize, lastSector = 512, 10
+	binary.LittleEndian.PutUint32(input[sectorSize+4*lastSector:], lastSector)
+	if _, err := docx.ReadDoc(input); !errors.Is(err, docx.ErrInvalidDoc) {
+		t.Errorf("ReadDoc() error = %v, want %v", err, docx.ErrInvalidDoc)
+	}
+}
@@ -4,160 +4,47 @@
 package docx
 
 import (
-	"archive/zip"
 	"bytes"
-	"errors"
-	"io"
-	"os"
-	"regexp"
-	"strings"
 )
 
-// ReadFile loads a Word document from a file, converts it to lower-case plain text, and returns the text as a byte slice.
-func ReadFile(path string) ([]byte, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-	return ReadBuffer(data)
-}
-
 // ReadBuffer loads a Word document from a byte slice, converts it to lower-case plain text, and returns the text as a byte slice.
 func ReadBuffer(data []byte) ([]byte, error) {
 	return Read(bytes.NewReader(data))
 }
 
 // Read reads a Word document, converts it to lower-case plain text, and returns the text as a byte slice.
+// It is a wrapper around Stream that collects the text in memory.
+//
+// Only the main document part is read. Running headers and footers are stored
+// in their own parts (word/header*.xml and word/footer*.xml) and are repeated on
+// every page, so reading them would interleave spurious lines between the units.
+// We can't tell the difference between a space and a tab, and we destroy all the
+// original Word tables.
 func Read(r *bytes.Reader) ([]byte, error) {
-	zr, err := zip.NewReader(r, r.Size())
-	if err != nil {
-		return nil, err
-	}
-
-	doc := docx{
-		zipFileReader: nil,
-		Files:         zr.File,
-		FilesContent:  map[string][]byte{},
-	}
-
-	for _, f := range doc.Files {
-		contents, _ := doc.retrieveFileContents(f.Name)
-		doc.FilesContent[f.Name] = contents
-	}
-
-	// convert the xml data to a slice of word tokens
-	doc.listP(string(doc.FilesContent["word/document.xml"]))
-
-	// convert the word tokens to a slice containing all the words.
-	// we collapse spaces into a single space and can't tell the difference between a space and a tab.
-	// we also destroy all the original Word tables.
 	result := &bytes.Buffer{}
-	for _, word := range doc.WordsList {
-		for column, content := range word.Content {
-			if column != 0 {
-				result.WriteByte(' ')
-			}
-			result.WriteString(strings.ToLower(content))
-		}
-		result.WriteByte('\n')
-	}
-
-	return scrubNonPrintingGlyphs(result.Bytes()), nil
-}
-
-// http://officeopenxml.com/anatomyofOOXML.php
-
-// copied from https://github.com/lu4p/cat/blob/master/docxtxt/docxreader.go
-// and licensed as provided in the COPYING file in this folder.
-
-// docx zip struct
-type docx struct {
-	zipFileReader *zip.ReadCloser
-	Files         []*zip.File
-	FilesContent  map[string][]byte
-	WordsList     []*words
-}
-
-type words struct {
-	Content []string
-}
-
-// read all files contents
-func (d *docx) retrieveFileContents(filename string) ([]byte, error) {
-	var file *zip.File
-	for _, f := range d.Files {
-		if f.Name == filename {
-			file = f
-		}
-	}
-
-	if file == nil {
-		return nil, errors.New(filename + " file not found")
-	}
-
-	reader, err := file.Open()
-	if err != nil {
+	if err := Stream(r, r.Size(), result); err != nil {
 		return nil, err
 	}
-
-	return io.ReadAll(reader)
+	return result.Bytes(), nil
 }
 
-var (
-	rxRunT = regexp.MustCompile(`(?U)(<w:r>|<w:r .*>)(.*)(</w:r>)`)
-	rxT    = regexp.MustCompile(`(?U)(<w:t>|<w:t .*>)(.*)(</w:t>)`)
-)
-
-// get w:t value
-func (d *docx) getT(item string) {
-	var subStr string
-	data := item
-	w := new(words)
-	content := []string{}
-
-	wrMatch := rxRunT.FindAllStringSubmatchIndex(data, -1)
-	// loop r
-	for _, rMatch := range wrMatch {
-		rData := data[rMatch[4]:rMatch[5]]
-		wtMatch := rxT.FindAllStringSubmatchIndex(rData, -1)
-		for _, match := range wtMatch {
-			subStr = rData[match[4]:match[5]]
-			content = append(content, subStr)
-		}
+// ReadBufferOriginalCase is ReadBuffer without the conversion to lower case.
+// Use it when the spelling of names in the document is needed.
+func ReadBufferOriginalCase(data []byte) ([]byte, error) {
+	result := &bytes.Buffer{}
+	if err := stream(bytes.NewReader(data), int64(len(data)), result, false); err != nil {
+		return nil, err
 	}
-	w.Content = content
-	d.WordsList = append(d.WordsList, w)
+	return result.Bytes(), nil
 }
 
-var (
-	rxP = regexp.MustCompile(`(?U)<w:p[^>]*>(.*)</w:p>`)
-)
-
-// hasP identify the paragraph
-func hasP(data string) bool {
-	result := rxP.MatchString(data)
-	return result
-}
+// http://officeopenxml.com/anatomyofOOXML.php
 
-var (
-	rxListP = regexp.MustCompile(`(?U)<w:p[^>]*(.*)</w:p>`)
+const (
+	// documentPart is the name of the part that contains the body of the document.
+	documentPart = "word/document.xml"
 )
 
-// listP for w:p tag value
-func (d *docx) listP(data string) {
-	var result []string
-	for _, match := range rxListP.FindAllStringSubmatch(data, -1) {
-		result = append(result, match[1])
-	}
-	for _, item := range result {
-		if hasP(item) {
-			d.listP(item)
-			continue
-		}
-		d.getT(item)
-	}
-}
-
 var (
 	// pre-computed lookup table for acceptable printing characters
 	isPrintingGlyph [256]bool
@@ -0,0 +1,216 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package docx_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"github.com/playbymail/tndocx/docx"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// newDocx returns a minimal Word document containing the given parts.
+func newDocx(t *testing.T, parts map[string][]byte) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for name, data := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		} else if _, err = w.Write(data); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+const (
+	documentXML = `<?xml version="1.0" encoding="%s" standalone="yes"?><w:document><w:body><w:p><w:r><w:t>Tribe 0138</w:t></w:r></w:p></w:body></w:document>`
+)
+
+func utf16le(s string) []byte {
+	buf := []byte{0xFF, 0xFE}
+	for _, u := range utf16.Encode([]rune(s)) {
+		buf = append(buf, byte(u), byte(u>>8))
+	}
+	return buf
+}
+
+func TestReadBufferEncoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		document []byte
+	}{
+		{name: "utf-8", document: []byte(fmt.Sprintf(documentXML, "UTF-8"))},
+		{name: "utf-8 with bom", document: append([]byte{0xEF, 0xBB, 0xBF}, fmt.Sprintf(documentXML, "UTF-8")...)},
+		{name: "utf-16 with bom", document: utf16le(fmt.Sprintf(documentXML, "UTF-16"))},
+		{name: "latin-1", document: []byte(fmt.Sprintf(documentXML, "ISO-8859-1"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := newDocx(t, map[string][]byte{"word/document.xml": tt.document})
+			got, err := docx.ReadBuffer(input)
+			if err != nil {
+				t.Fatalf("ReadBuffer() error = %v", err)
+			}
+			if want := "tribe 0138\n"; string(got) != want {
+				t.Errorf("ReadBuffer() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestReadBufferUnsupportedEncoding(t *testing.T) {
+	input := newDocx(t, map[string][]byte{"word/document.xml": []byte(fmt.Sprintf(documentXML, "EBCDIC"))})
+	if _, err := docx.ReadBuffer(input); err != docx.ErrUnsupportedEncoding {
+		t.Errorf("ReadBuffer() error = %v, want %v", err, docx.ErrUnsupportedEncoding)
+	}
+}
+
+func TestDetectEncoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		expected string
+	}{
+		{name: "no declaration", input: []byte("<w:document/>"), expected: "utf-8"},
+		{name: "declared", input: []byte(`<?xml version="1.0" encoding="ISO-8859-1"?>`), expected: "iso-8859-1"},
+		{name: "utf-8 bom", input: []byte("\xEF\xBB\xBF<?xml version=\"1.0\"?>"), expected: "utf-8"},
+		{name: "utf-16le bom", input: []byte("\xFF\xFE<\x00"), expected: "utf-16le"},
+		{name: "utf-16be bom", input: []byte("\xFE\xFF\x00<"), expected: "utf-16be"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := docx.DetectEncoding(tt.input); got != tt.expected {
+				t.Errorf("DetectEncoding() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReadBufferExcludesHeadersAndFooters(t *testing.T) {
+	header := `<?xml version="1.0" encoding="UTF-8"?><w:hdr><w:p><w:r><w:t>Turn Report Page Header</w:t></w:r></w:p></w:hdr>`
+	footer := `<?xml version="1.0" encoding="UTF-8"?><w:ftr><w:p><w:r><w:t>Page Footer</w:t></w:r></w:p></w:ftr>`
+	input := newDocx(t, map[string][]byte{
+		"word/document.xml": []byte(fmt.Sprintf(documentXML, "UTF-8")),
+		"word/header1.xml":  []byte(header),
+		"word/footer1.xml":  []byte(footer),
+	})
+	got, err := docx.ReadBuffer(input)
+	if err != nil {
+		t.Fatalf("ReadBuffer() error = %v", err)
+	}
+	if bytes.Contains(got, []byte("header")) || bytes.Contains(got, []byte("footer")) {
+		t.Errorf("ReadBuffer() = %q, want no header or footer text", got)
+	}
+	if want := "tribe 0138\n"; string(got) != want {
+		t.Errorf("ReadBuffer() = %q, want %q", got, want)
+	}
+}
+
+func TestDetectAuthoringTool(t *testing.T) {
+	document := []byte(`<?xml version="1.0" encoding="UTF-8"?><w:document><w:body><w:p><w:r><w:t>Tribe 0138</w:t><w:br/><w:t>Current Turn 900-04</w:t></w:r></w:p></w:body></w:document>`)
+	app := func(name string) []byte {
+		return []byte(`<?xml version="1.0" encoding="UTF-8"?><Properties><Application>` + name + `</Application></Properties>`)
+	}
+	tests := []struct {
+		name  string
+		input []byte
+		tool  string
+		text  string
+	}{
+		{name: "word", input: newDocx(t, map[string][]byte{"word/document.xml": document, "docProps/app.xml": app("Microsoft Office Word")}), tool: docx.ToolWord, text: "tribe 0138\ncurrent turn 900-04\n"},
+		{name: "libreoffice", input: newDocx(t, map[string][]byte{"word/document.xml": document, "docProps/app.xml": app("LibreOffice/7.6.4.1$Linux_X86_64")}), tool: docx.ToolLibreOffice, text: "tribe 0138\ncurrent turn 900-04\n"},
+		{name: "google docs", input: newDocx(t, map[string][]byte{"word/document.xml": document, "docProps/app.xml": app("Google Docs")}), tool: docx.ToolGoogleDocs, text: "tribe 0138\ncurrent turn 900-04\n"},
+		{name: "no application properties", input: newDocx(t, map[string][]byte{"word/document.xml": document}), tool: docx.ToolUnknown, text: "tribe 0138\ncurrent turn 900-04\n"},
+		{name: "plain text", input: []byte("tribe 0138\n"), tool: docx.ToolText},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := docx.DetectAuthoringTool(tt.input); got != tt.tool {
+				t.Errorf("DetectAuthoringTool() = %q, want %q", got, tt.tool)
+			}
+			if tt.text == "" {
+				return
+			}
+			got, err := docx.ReadBuffer(tt.input)
+			if err != nil {
+				t.Fatalf("ReadBuffer() error = %v", err)
+			}
+			if string(got) != tt.text {
+				t.Errorf("ReadBuffer() = %q, want %q", got, tt.text)
+			}
+		})
+	}
+}
+
+func TestCreatedDate(t *testing.T) {
+	document := []byte(`<?xml version="1.0" encoding="UTF-8"?><w:document><w:body><w:p><w:r><w:t>Tribe 0138</w:t></w:r></w:p></w:body></w:document>`)
+	core := []byte(`<?xml version="1.0" encoding="UTF-8"?><cp:coreProperties><dcterms:created xsi:type="dcterms:W3CDTF">2024-02-14T18:30:00-05:00</dcterms:created></cp:coreProperties>`)
+
+	created, ok := docx.CreatedDate(newDocx(t, map[string][]byte{"word/document.xml": document, "docProps/core.xml": core}))
+	if want := time.Date(2024, 2, 14, 23, 30, 0, 0, time.UTC); !ok || !created.Equal(want) {
+		t.Errorf("CreatedDate() = %v, %v, want %v, true", created, ok, want)
+	}
+	if _, ok := docx.CreatedDate(newDocx(t, map[string][]byte{"word/document.xml": document})); ok {
+		t.Errorf("CreatedDate() without core properties = true, want false")
+	}
+	if _, ok := docx.CreatedDate([]byte("tribe 0138\n")); ok {
+		t.Errorf("CreatedDate() for text = true, want false")
+	}
+}
+
+// countingWriter counts the calls to Write.
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestStream(t *testing.T) {
+	body := &bytes.Buffer{}
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(body, `<w:p><w:pPr/><w:r><w:t>Tribe %04d</w:t></w:r><w:r><w:t xml:space="preserve">Salt &amp; Iron</w:t></w:r></w:p>`, i)
+	}
+	input := newDocx(t, map[string][]byte{
+		"word/document.xml": []byte(`<?xml version="1.0" encoding="UTF-8"?><w:document><w:body>` + body.String() + `</w:body></w:document>`),
+		"docProps/app.xml":  []byte(`<Properties><Application>Microsoft Office Word</Application></Properties>`),
+	})
+
+	w := &countingWriter{}
+	if err := docx.Stream(bytes.NewReader(input), int64(len(input)), w); err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	// each paragraph is written as soon as it ends
+	if w.writes != 1000 {
+		t.Errorf("Stream() wrote %d times, want 1000", w.writes)
+	}
+	if want := "tribe 0999 salt & iron\n"; !bytes.HasSuffix(w.Bytes(), []byte(want)) {
+		t.Errorf("Stream() ends with %q, want %q", w.Bytes()[w.Len()-len(want):], want)
+	}
+	text, err := docx.ReadBuffer(input)
+	if err != nil {
+		t.Fatalf("ReadBuffer() error = %v", err)
+	}
+	if !bytes.Equal(text, w.Bytes()) {
+		t.Errorf("ReadBuffer() does not match Stream()")
+	}
+	text, err = docx.ReadBufferOriginalCase(input)
+	if err != nil {
+		t.Fatalf("ReadBufferOriginalCase() error = %v", err)
+	}
+	if want := "Tribe 0999 Salt & Iron\n"; !bytes.HasSuffix(text, []byte(want)) {
+		t.Errorf("ReadBufferOriginalCase() ends with %q, want %q", text[len(text)-len(want):], want)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package docx
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	ErrUnsupportedEncoding = errors.New("unsupported encoding")
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+
+	// rxXMLEncoding captures the encoding from the XML declaration.
+	// it looks like: <?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+	rxXMLEncoding = regexp.MustCompile(`^<\?xml[^>]*\sencoding\s*=\s*["']([A-Za-z0-9._-]+)["']`)
+)
+
+// DetectEncoding returns the lower-case name of the encoding of an XML part.
+// A byte order mark takes precedence over the encoding in the XML declaration.
+// If there is neither, the XML default of "utf-8" is returned.
+func DetectEncoding(data []byte) string {
+	if bytes.HasPrefix(data, bomUTF8) {
+		return "utf-8"
+	} else if bytes.HasPrefix(data, bomUTF16BE) {
+		return "utf-16be"
+	} else if bytes.HasPrefix(data, bomUTF16LE) {
+		return "utf-16le"
+	} else if len(data) > 1 && data[0] == 0 && data[1] == '<' {
+		// utf-16 without a byte order mark
+		return "utf-16be"
+	} else if len(data) > 1 && data[0] == '<' && data[1] == 0 {
+		// utf-16 without a byte order mark
+		return "utf-16le"
+	} else if match := rxXMLEncoding.FindSubmatch(data); match != nil {
+		return strings.ToLower(string(match[1]))
+	}
+	return "utf-8"
+}
+
+// DecodeXML converts an XML part to UTF-8 using the encoding returned by
+// DetectEncoding. Any byte order mark is removed. Returns an error if the
+// encoding is not supported.
+func DecodeXML(data []byte) ([]byte, error) {
+	switch DetectEncoding(data) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return bytes.TrimPrefix(data, bomUTF8), nil
+	case "utf-16":
+		// declared as utf-16 but without a byte order mark or null bytes,
+		// so the declaration is wrong and the data is really 8-bit.
+		return data, nil
+	case "utf-16be":
+		return decodeUTF16(bytes.TrimPrefix(data, bomUTF16BE), false), nil
+	case "utf-16le":
+		return decodeUTF16(bytes.TrimPrefix(data, bomUTF16LE), true), nil
+	case "iso-8859-1", "latin1", "latin-1":
+		return decodeLatin1(data), nil
+	}
+	return nil, ErrUnsupportedEncoding
+}
+
+// decodeLatin1 converts ISO-8859-1 to UTF-8.
+// Every byte in ISO-8859-1 maps directly to the rune with the same value.
+func decodeLatin1(data []byte) []byte {
+	output := bytes.NewBuffer(make([]byte, 0, len(data)))
+	for _, b := range data {
+		output.WriteRune(rune(b))
+	}
+	return output.Bytes()
+}
+
+// decodeUTF16 converts UTF-16 to UTF-8.
+// A trailing odd byte is discarded.
+func decodeUTF16(data []byte, littleEndian bool) []byte {
+	units := make([]uint16, 0, len(data)/2)
+	for ; len(data) > 1; data = data[2:] {
+		if littleEndian {
+			units = append(units, uint16(data[0])|uint16(data[1])<<8)
+		} else {
+			units = append(units, uint16(data[0])<<8|uint16(data[1]))
+		}
+	}
+	output := make([]byte, 0, len(units))
+	for _, r := range utf16.Decode(units) {
+		output = utf8.AppendRune(output, r)
+	}
+	return output
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// the authoring tools that DetectAuthoringTool reports.
+const (
+	ToolGoogleDocs  = "google docs"
+	ToolLibreOffice = "libreoffice"
+	ToolText        = "text"
+	ToolUnknown     = "unknown"
+	ToolWord        = "word"
+)
+
+const (
+	// appPart is the name of the part that contains the application properties.
+	appPart = "docProps/app.xml"
+	// corePart is the name of the part that contains the core properties.
+	corePart = "docProps/core.xml"
+)
+
+var (
+	rxApplication = regexp.MustCompile(`<Application>([^<]*)</Application>`)
+	rxCreated     = regexp.MustCompile(`<dcterms:created[^>]*>([^<]*)</dcterms:created>`)
+)
+
+// CreatedDate returns the date the document was created from the docProps/core.xml part.
+// Returns false if the input isn't a Word document or doesn't record a valid date.
+func CreatedDate(input []byte) (time.Time, bool) {
+	if DetectWordDocType(input) != Docx {
+		return time.Time{}, false
+	}
+	zr, err := zip.NewReader(bytes.NewReader(input), int64(len(input)))
+	if err != nil {
+		return time.Time{}, false
+	}
+	for _, f := range zr.File {
+		if f.Name != corePart {
+			continue
+		}
+		data, err := readPart(f)
+		if err != nil {
+			return time.Time{}, false
+		}
+		match := rxCreated.FindSubmatch(data)
+		if match == nil {
+			return time.Time{}, false
+		}
+		created, err := time.Parse(time.RFC3339, strings.TrimSpace(string(match[1])))
+		if err != nil {
+			return time.Time{}, false
+		}
+		return created.UTC(), true
+	}
+	return time.Time{}, false
+}
+
+// readPart returns the contents of a part of the archive.
+func readPart(f *zip.File) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// DetectAuthoringTool returns the tool that created the input.
+// Word documents report the application that saved them in the docProps/app.xml part.
+// A document without that part, like a Google Docs export or a hand-built archive,
+// is reported as ToolUnknown. Input that isn't a Word document is assumed to come
+// from a text editor.
+func DetectAuthoringTool(input []byte) string {
+	switch DetectWordDocType(input) {
+	case Doc:
+		return ToolWord
+	case Docx:
+		zr, err := zip.NewReader(bytes.NewReader(input), int64(len(input)))
+		if err != nil {
+			return ToolUnknown
+		}
+		return authoringTool(zr.File)
+	}
+	return ToolText
+}
+
+// authoringTool returns the tool that created the document from the files in the archive.
+func authoringTool(files []*zip.File) string {
+	for _, f := range files {
+		if f.Name != appPart {
+			continue
+		}
+		data, err := readPart(f)
+		if err != nil {
+			return ToolUnknown
+		}
+		match := rxApplication.FindSubmatch(data)
+		if match == nil {
+			return ToolUnknown
+		}
+		switch application := strings.ToLower(string(match[1])); {
+		case strings.Contains(application, "google"):
+			return ToolGoogleDocs
+		case strings.Contains(application, "libreoffice"):
+			return ToolLibreOffice
+		case strings.Contains(application, "microsoft"):
+			return ToolWord
+		}
+		return ToolUnknown
+	}
+	return ToolUnknown
+}
@@ -0,0 +1,236 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package docx
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Stream reads a Word document and writes it to w as lower-case plain text,
+// one line per paragraph. The main document part is decompressed and tokenized
+// as it is read, and each line is written as soon as its paragraph ends, so the
+// memory used doesn't depend on the size of the document.
+//
+// The document is read through an io.ReaderAt because the zip directory is at
+// the end of the file. An *os.File works, so documents don't need to be loaded
+// into memory first.
+func Stream(r io.ReaderAt, size int64, w io.Writer) error {
+	return stream(r, size, w, true)
+}
+
+// stream writes the text of a Word document to w, converting it to lower case if lower is set.
+func stream(r io.ReaderAt, size int64, w io.Writer, lower bool) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	var part *zip.File
+	for _, f := range zr.File {
+		if f.Name == documentPart {
+			part = f
+		}
+	}
+	if part == nil {
+		return errors.New(documentPart + " file not found")
+	}
+	rc, err := part.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	// convert the document to utf-8 so that the byte order mark and declared encoding don't leak into the text
+	document, err := decodeXMLReader(bufio.NewReader(rc))
+	if err != nil {
+		return err
+	}
+
+	d := xml.NewDecoder(document)
+	d.Strict = false
+	// the part has already been converted to utf-8, so the declared encoding is ignored
+	d.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	// paragraphs collects the text for each open paragraph. a paragraph may
+	// contain another (for example, in a text box), so they are kept on a stack.
+	var paragraphs [][]string
+	inRun, inText := 0, 0
+	writeLine := func(content []string) error {
+		text := strings.Join(content, " ")
+		if lower {
+			text = strings.ToLower(text)
+		}
+		line := []byte(text + "\n")
+		_, err := w.Write(scrubNonPrintingGlyphs(line))
+		return err
+	}
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				paragraphs = append(paragraphs, []string{})
+			case "r":
+				inRun++
+			case "t":
+				if inRun != 0 && len(paragraphs) != 0 {
+					inText++
+					paragraphs[len(paragraphs)-1] = append(paragraphs[len(paragraphs)-1], "")
+				}
+			case "br":
+				// some tools, like google docs, end lines with a break inside the paragraph
+				// instead of starting a new paragraph, so every break ends the line.
+				if len(paragraphs) != 0 {
+					if err := writeLine(paragraphs[len(paragraphs)-1]); err != nil {
+						return err
+					}
+					paragraphs[len(paragraphs)-1] = []string{}
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p":
+				if len(paragraphs) != 0 {
+					if err := writeLine(paragraphs[len(paragraphs)-1]); err != nil {
+						return err
+					}
+					paragraphs = paragraphs[:len(paragraphs)-1]
+				}
+			case "r":
+				if inRun != 0 {
+					inRun--
+				}
+			case "t":
+				if inText != 0 {
+					inText--
+				}
+			}
+		case xml.CharData:
+			if inText != 0 {
+				content := paragraphs[len(paragraphs)-1]
+				content[len(content)-1] += string(t)
+			}
+		}
+	}
+	return nil
+}
+
+// ReadFile loads a Word document from a file, converts it to lower-case plain text, and returns the text as a byte slice.
+// The document is streamed from the file rather than loaded into memory.
+func ReadFile(path string) ([]byte, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	fi, err := fp.Stat()
+	if err != nil {
+		return nil, err
+	}
+	result := &bytes.Buffer{}
+	if err := Stream(fp, fi.Size(), result); err != nil {
+		return nil, err
+	}
+	return result.Bytes(), nil
+}
+
+// decodeXMLReader is DecodeXML for a stream.
+// The encoding is detected from the start of the part, which is left in the reader.
+func decodeXMLReader(r *bufio.Reader) (io.Reader, error) {
+	head, _ := r.Peek(512)
+	switch DetectEncoding(head) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		if bytes.HasPrefix(head, bomUTF8) {
+			_, _ = r.Discard(len(bomUTF8))
+		}
+		return r, nil
+	case "utf-16":
+		// declared as utf-16 but without a byte order mark or null bytes,
+		// so the declaration is wrong and the data is really 8-bit.
+		return r, nil
+	case "utf-16be":
+		if bytes.HasPrefix(head, bomUTF16BE) {
+			_, _ = r.Discard(len(bomUTF16BE))
+		}
+		return &runeReader{next: utf16Runes(r, false)}, nil
+	case "utf-16le":
+		if bytes.HasPrefix(head, bomUTF16LE) {
+			_, _ = r.Discard(len(bomUTF16LE))
+		}
+		return &runeReader{next: utf16Runes(r, true)}, nil
+	case "iso-8859-1", "latin1", "latin-1":
+		return &runeReader{next: func() (rune, error) {
+			b, err := r.ReadByte()
+			return rune(b), err
+		}}, nil
+	}
+	return nil, ErrUnsupportedEncoding
+}
+
+// runeReader is an io.Reader that encodes runes from next as UTF-8.
+type runeReader struct {
+	next func() (rune, error)
+	buf  []byte
+	err  error
+}
+
+func (rr *runeReader) Read(p []byte) (int, error) {
+	for len(rr.buf) < len(p) && rr.err == nil {
+		var ch rune
+		if ch, rr.err = rr.next(); rr.err == nil {
+			rr.buf = utf8.AppendRune(rr.buf, ch)
+		}
+	}
+	n := copy(p, rr.buf)
+	rr.buf = append(rr.buf[:0], rr.buf[n:]...)
+	if n == 0 {
+		return 0, rr.err
+	}
+	return n, nil
+}
+
+// utf16Runes returns a function that reads the next rune from UTF-16 input.
+// A trailing odd byte is discarded.
+func utf16Runes(r *bufio.Reader, littleEndian bool) func() (rune, error) {
+	unit := func() (uint16, error) {
+		var pair [2]byte
+		if _, err := io.ReadFull(r, pair[:]); err == io.ErrUnexpectedEOF {
+			return 0, io.EOF
+		} else if err != nil {
+			return 0, err
+		}
+		if littleEndian {
+			return uint16(pair[0]) | uint16(pair[1])<<8, nil
+		}
+		return uint16(pair[0])<<8 | uint16(pair[1]), nil
+	}
+	return func() (rune, error) {
+		u, err := unit()
+		if err != nil {
+			return 0, err
+		} else if !utf16.IsSurrogate(rune(u)) {
+			return rune(u), nil
+		}
+		low, err := unit()
+		if err != nil {
+			return utf8.RuneError, nil
+		}
+		return utf16.DecodeRune(rune(u), rune(low)), nil
+	}
+}
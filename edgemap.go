@@ -0,0 +1,133 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"slices"
+	"sort"
+)
+
+// HexEdge is a feature, like a river or a ford, on the edge between two adjacent hexes.
+// The edge is described from the side of the hex whose canonical form sorts first,
+// so the same edge reported from either side has the same Hex and Direction.
+// UnitIds are the units that reported the edge, in sorted order.
+type HexEdge struct {
+	Hex        HexCoordinate `json:"hex"`
+	Direction  string        `json:"direction"`
+	Type       string        `json:"type"`
+	Impassable bool          `json:"impassable,omitempty"`
+	UnitIds    []string      `json:"unit-ids"`
+}
+
+// EdgeContradiction is an edge that some units reported and others didn't,
+// even though they reported the edges of a hex on one side of it.
+// MissingFrom are the units that didn't report the edge, in sorted order.
+type EdgeContradiction struct {
+	Edge        HexEdge  `json:"edge"`
+	MissingFrom []string `json:"missing-from"`
+}
+
+// edgeKey identifies an edge feature from its canonical side.
+type edgeKey struct {
+	hex       string
+	direction string
+	edgeType  string
+}
+
+// MergeEdges returns the edges reported by the units in the report, with the reports
+// from both sides of an edge merged into one. A unit reports the edges of each hex it
+// enters while moving and of the hex its status line describes. A unit that reports
+// the edges of a hex on either side of an edge but not the edge itself contradicts
+// the units that did report it.
+//
+// Water edges ("o ne") describe the terrain of the neighboring hex rather than a
+// shared feature, so they are not included.
+// Edges and contradictions are sorted by hex, direction, and type.
+func (r *Report) MergeEdges() (edges []HexEdge, contradictions []EdgeContradiction) {
+	merged := map[edgeKey]*HexEdge{}
+	// covered is the set of units that reported the edges of each hex
+	covered := map[string]map[string]bool{}
+	report := func(hex HexCoordinate, unitId string, reported []*Edge) {
+		if covered[hex.String()] == nil {
+			covered[hex.String()] = map[string]bool{}
+		}
+		covered[hex.String()][unitId] = true
+		for _, edge := range reported {
+			if isWaterEdge(edge.Type) {
+				continue
+			}
+			for _, direction := range edge.Directions {
+				side, sideDirection := hex, direction
+				if neighbor, ok := hex.Neighbor(direction); ok && neighbor.String() < hex.String() {
+					side, sideDirection = neighbor, oppositeDirection[direction]
+				}
+				key := edgeKey{hex: side.String(), direction: sideDirection, edgeType: edge.Type}
+				he, ok := merged[key]
+				if !ok {
+					he = &HexEdge{Hex: side, Direction: sideDirection, Type: edge.Type}
+					merged[key] = he
+				}
+				he.Impassable = he.Impassable || edge.Impassable
+				if !slices.Contains(he.UnitIds, unitId) {
+					he.UnitIds = append(he.UnitIds, unitId)
+				}
+			}
+		}
+	}
+	for _, id := range r.sortedUnitIds() {
+		unit := r.Units[id]
+		hex, known := parseHexCoordinate(unit.From)
+		for _, step := range unit.Moves {
+			if step.Kind != StepMove || step.Still || !known {
+				continue
+			} else if hex, known = hex.Neighbor(step.Direction); known {
+				report(hex, unit.Id, step.Edges)
+			}
+		}
+		if hex, ok := parseHexCoordinate(unit.To); ok && unit.Status != nil {
+			report(hex, unit.Id, unit.Status.Edges)
+		}
+	}
+
+	for _, he := range merged {
+		sort.Strings(he.UnitIds)
+		edges = append(edges, *he)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if a, b := edges[i].Hex.String(), edges[j].Hex.String(); a != b {
+			return a < b
+		} else if edges[i].Direction != edges[j].Direction {
+			return edges[i].Direction < edges[j].Direction
+		}
+		return edges[i].Type < edges[j].Type
+	})
+	for _, he := range edges {
+		sides := []string{he.Hex.String()}
+		if neighbor, ok := he.Hex.Neighbor(he.Direction); ok {
+			sides = append(sides, neighbor.String())
+		}
+		var missing []string
+		for _, side := range sides {
+			for unitId := range covered[side] {
+				if !slices.Contains(he.UnitIds, unitId) && !slices.Contains(missing, unitId) {
+					missing = append(missing, unitId)
+				}
+			}
+		}
+		if missing != nil {
+			sort.Strings(missing)
+			contradictions = append(contradictions, EdgeContradiction{Edge: he, MissingFrom: missing})
+		}
+	}
+	return edges, contradictions
+}
+
+// isWaterEdge returns true if the edge type is one of the water terrains.
+func isWaterEdge(edgeType string) bool {
+	for _, water := range waterEdges {
+		if edgeType == water {
+			return true
+		}
+	}
+	return false
+}
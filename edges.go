@@ -0,0 +1,51 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"strings"
+)
+
+// parseEdges returns the edges found in a list of comma-separated segments,
+// along with the segments that aren't edges.
+// An edge is a water code ("o ne") or an edge name from the vocabulary
+// ("river se s") followed by one or more directions. Directions may be
+// separated by spaces or by commas, so a segment that is only directions
+// is added to the preceding edge.
+func (p *Parser) parseEdges(segments []string) (edges []*Edge, other []string) {
+	var edge *Edge
+	for _, segment := range segments {
+		fields := strings.Fields(segment)
+		if len(fields) == 0 {
+			edge = nil
+			continue
+		}
+		if e, ok := p.parseEdge(fields); ok {
+			edge = e
+			edges = append(edges, edge)
+		} else if edge != nil && isDirectionList(fields) {
+			edge.Directions = append(edge.Directions, fields...)
+		} else {
+			edge = nil
+			other = append(other, strings.Join(fields, " "))
+		}
+	}
+	return edges, other
+}
+
+// parseEdge returns the edge if the fields are an edge type followed by a list of directions.
+// Edge names may contain spaces ("stone road"), so we check every prefix of the fields.
+// A seasonal variant of an edge ("dry riverbed") is reported as the base edge.
+func (p *Parser) parseEdge(fields []string) (*Edge, bool) {
+	if edgeType, ok := waterEdges[fields[0]]; ok && isDirectionList(fields[1:]) {
+		return &Edge{Type: edgeType, Directions: fields[1:]}, true
+	}
+	for n := 1; n < len(fields); n++ {
+		if name := strings.Join(fields[:n], " "); p.vocabulary.Edges[name] && isDirectionList(fields[n:]) {
+			return &Edge{Type: name, Directions: fields[n:]}, true
+		} else if seasonal, ok := p.vocabulary.Seasonal[name]; ok && p.vocabulary.Edges[seasonal.Base] && isDirectionList(fields[n:]) {
+			return &Edge{Type: seasonal.Base, Directions: fields[n:], Seasonal: name}, true
+		}
+	}
+	return nil, false
+}
@@ -7,11 +7,20 @@ type Error string
 func (e Error) Error() string { return string(e) }
 
 const (
+	ErrDuplicateName        = Error("duplicate name")
 	ErrEmptyInput           = Error("empty input")
+	ErrHexOutOfRange        = Error("hex out of range")
 	ErrInvalidElementId     = Error("invalid element id")
+	ErrInvalidHex           = Error("invalid hex")
+	ErrInvalidOption        = Error("invalid option")
 	ErrMissingElementHeader = Error("missing element header")
 	ErrMissingField         = Error("missing field")
+	ErrMissingTurnHeader    = Error("missing turn header")
 	ErrNotImplemented       = Error("not implemented")
+	ErrTurnMismatch         = Error("turn mismatch")
 	ErrUnexpectedInput      = Error("unexpected input")
+	ErrUnitNotFound         = Error("unit not found")
 	ErrUnknownFormat        = Error("unknown format")
+	ErrUnknownHex           = Error("unknown hex")
+	ErrUnsupportedSchema    = Error("unsupported schema version")
 )
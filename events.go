@@ -0,0 +1,78 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"regexp"
+	"strings"
+)
+
+// EventKind is the kind of thing that happened to a unit during the turn.
+type EventKind string
+
+const (
+	EventFormation EventKind = "formation"
+	EventTransfer  EventKind = "transfer"
+	EventDisband   EventKind = "disband"
+)
+
+// Event is something that happened to a unit during the turn, other than movement.
+// UnitId is the unit the event happened to. OtherUnitId is the unit on the other
+// side of the event, if any: the parent of a new unit, the unit that absorbed a
+// disbanded one, or the unit that received a transfer.
+// Line is the line number in the input, starting at 1.
+type Event struct {
+	Kind        EventKind `json:"kind"`
+	Line        int       `json:"line,omitempty"`
+	UnitId      string    `json:"unit-id,omitempty"`
+	OtherUnitId string    `json:"other-unit-id,omitempty"`
+	Goods       string    `json:"goods,omitempty"` // set on transfers
+}
+
+var (
+	// rxFormationLine captures the new unit and the unit that formed it.
+	// these look like:
+	// - 0138e1 formed
+	// - 0138e1 formed from 0138
+	rxFormationLine = regexp.MustCompile(`^(\d{4}(?:[cdefg]\d+)?) (?:was )?formed(?: (?:by|from) (\d{4}(?:[cdefg]\d+)?))?$`)
+
+	// rxDisbandLine captures the disbanded unit and the unit that absorbed it.
+	// these look like:
+	// - 0138e1 disbanded
+	// - 0138e1 disbanded into 0138
+	rxDisbandLine = regexp.MustCompile(`^(\d{4}(?:[cdefg]\d+)?) (?:was )?disbanded(?: into (\d{4}(?:[cdefg]\d+)?))?$`)
+
+	// rxTransferLine captures the goods and the units on either side of a transfer.
+	// these look like:
+	// - transfer 100 grain from 0138 to 0138e1
+	// - transferred 20 horses to 0138e1
+	rxTransferLine = regexp.MustCompile(`^transfer(?:red)?:? (.+?) (?:from (\d{4}(?:[cdefg]\d+)?) )?to (\d{4}(?:[cdefg]\d+)?)$`)
+)
+
+// parseEvent returns the event reported on a line.
+// A transfer that doesn't name the unit it came from is credited to the current unit.
+// Returns false if the line does not report an event.
+func parseEvent(line []byte, currentUnitId string) (Event, bool) {
+	if match := rxFormationLine.FindSubmatch(line); match != nil {
+		return Event{Kind: EventFormation, UnitId: string(match[1]), OtherUnitId: string(match[2])}, true
+	} else if match := rxDisbandLine.FindSubmatch(line); match != nil {
+		return Event{Kind: EventDisband, UnitId: string(match[1]), OtherUnitId: string(match[2])}, true
+	} else if match := rxTransferLine.FindSubmatch(line); match != nil {
+		event := Event{Kind: EventTransfer, UnitId: string(match[2]), OtherUnitId: string(match[3]), Goods: strings.TrimSpace(string(match[1]))}
+		if event.UnitId == "" {
+			event.UnitId = currentUnitId
+		}
+		return event, true
+	}
+	return Event{}, false
+}
+
+// EventsOfKind returns the events of the given kind in the order they were reported.
+func (r *Report) EventsOfKind(kind EventKind) (events []Event) {
+	for _, event := range r.Events {
+		if event.Kind == kind {
+			events = append(events, event)
+		}
+	}
+	return events
+}
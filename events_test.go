@@ -0,0 +1,31 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"github.com/playbymail/tndocx"
+	"reflect"
+	"testing"
+)
+
+func TestReportEvents(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		"0138e1 Formed from 0138",
+		"Transfer 100 Grain to 0138e1",
+		"0138c1 Disbanded into 0138",
+		"Transferred 20 Horses from 0138e1 to 0138",
+	)
+	want := []tndocx.Event{
+		{Kind: tndocx.EventFormation, Line: 2, UnitId: "0138e1", OtherUnitId: "0138"},
+		{Kind: tndocx.EventTransfer, Line: 3, UnitId: "0138", OtherUnitId: "0138e1", Goods: "100 grain"},
+		{Kind: tndocx.EventDisband, Line: 4, UnitId: "0138c1", OtherUnitId: "0138"},
+		{Kind: tndocx.EventTransfer, Line: 5, UnitId: "0138e1", OtherUnitId: "0138", Goods: "20 horses"},
+	}
+	if !reflect.DeepEqual(r.Events, want) {
+		t.Errorf("Events = %+v, want %+v", r.Events, want)
+	}
+	if transfers := r.EventsOfKind(tndocx.EventTransfer); !reflect.DeepEqual(transfers, []tndocx.Event{want[1], want[3]}) {
+		t.Errorf("EventsOfKind(transfer) = %+v, want %+v", transfers, []tndocx.Event{want[1], want[3]})
+	}
+}
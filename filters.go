@@ -5,6 +5,10 @@ package tndocx
 import (
 	"bytes"
 	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"unicode/utf8"
 )
 
@@ -17,27 +21,31 @@ const (
 )
 
 var (
-	rxUnitCourier  = regexp.MustCompile(`^courier \d{4}c\d$`)
-	rxUnitElement  = regexp.MustCompile(`^element \d{4}e\d$`)
-	rxUnitFleet    = regexp.MustCompile(`^fleet \d{4}f\d$`)
-	rxUnitGarrison = regexp.MustCompile(`^garrison \d{4}g\d$`)
-	rxUnitTribe    = regexp.MustCompile(`^tribe \d{4}$`)
-
-	rxCourierHeader  = regexp.MustCompile(`^courier \d{4}c\d,`)
-	rxElementHeader  = regexp.MustCompile(`^element \d{4}e\d,`)
-	rxFleetHeader    = regexp.MustCompile(`^fleet \d{4}f\d,`)
-	rxGarrisonHeader = regexp.MustCompile(`^garrison \d{4}g\d,`)
+	// the rxUnit expressions capture the unit id from the first field of a unit header.
+	rxUnitCourier  = regexp.MustCompile(`^courier (\d{4}c\d+)$`)
+	rxUnitElement  = regexp.MustCompile(`^element (\d{4}e\d+)$`)
+	rxUnitFleet    = regexp.MustCompile(`^fleet (\d{4}f\d+)$`)
+	rxUnitGarrison = regexp.MustCompile(`^garrison (\d{4}g\d+)$`)
+	rxUnitTribe    = regexp.MustCompile(`^tribe (\d{4})$`)
+
+	rxCourierHeader  = regexp.MustCompile(`^courier \d{4}c\d+,`)
+	rxElementHeader  = regexp.MustCompile(`^element \d{4}e\d+,`)
+	rxFleetHeader    = regexp.MustCompile(`^fleet \d{4}f\d+,`)
+	rxGarrisonHeader = regexp.MustCompile(`^garrison \d{4}g\d+,`)
 	rxTribeHeader    = regexp.MustCompile(`^tribe \d{4},`)
 
-	rxTurnHeader = regexp.MustCompile(`^current turn \d{3,4}-\d{1,2}\(#\d+\),`)
+	rxNoReport = regexp.MustCompile(`^no report\.?$`)
+
+	rxTurnHeader = regexp.MustCompile(`^current turn ?(?:\d{3,4}-\d{1,2}|\(#\d+\)|#\d+)`)
 
 	rxFleetMovement = regexp.MustCompile(`^(calm|mild|strong|gale) (ne|se|sw|nw|n|s) fleet movement:`)
-	rxScoutLine     = regexp.MustCompile(`^scout [1-8]:`)
+	rxScoutLine     = regexp.MustCompile(`^scouts? (\d+):`)
 
-	rxCourierStatus  = regexp.MustCompile(`^\d{4}c\d status:`)
-	rxElementStatus  = regexp.MustCompile(`^\d{4}e\d status:`)
-	rxFleetStatus    = regexp.MustCompile(`^\d{4}f\d status:`)
-	rxGarrisonStatus = regexp.MustCompile(`^\d{4}g\d status:`)
+	rxCourierStatus  = regexp.MustCompile(`^\d{4}c\d+ status:`)
+	rxDetachStatus   = regexp.MustCompile(`^\d{4}d\d+ status:`)
+	rxElementStatus  = regexp.MustCompile(`^\d{4}e\d+ status:`)
+	rxFleetStatus    = regexp.MustCompile(`^\d{4}f\d+ status:`)
+	rxGarrisonStatus = regexp.MustCompile(`^\d{4}g\d+ status:`)
 	rxTribeStatus    = regexp.MustCompile(`^\d{4} status:`)
 )
 
@@ -50,21 +58,38 @@ func IsMovementLine(line []byte) bool {
 }
 
 // IsScoutLine determines if a line represents a TribeNet scout command.
+// Scouts are numbered 1 through DefaultMaxScouts. Some reports spell it "scouts".
 // Example: "scout 1: scout s-pr"
 func IsScoutLine(line []byte) bool {
-	return rxScoutLine.Match(line)
+	return isScoutLine(line, DefaultMaxScouts)
+}
+
+// isScoutLine determines if a line represents a scout command for a scout numbered 1 through maxScouts.
+func isScoutLine(line []byte, maxScouts int) bool {
+	match := rxScoutLine.FindSubmatch(line)
+	if match == nil {
+		return false
+	}
+	n, err := strconv.Atoi(string(match[1]))
+	return err == nil && 1 <= n && n <= maxScouts
 }
 
 func IsTribeFollows(line []byte) bool {
-	return bytes.HasPrefix(line, []byte("tribe follows "))
+	return bytes.HasPrefix(line, prefixTribeFollows)
 }
 
 func IsTribeGoesTo(line []byte) bool {
-	return bytes.HasPrefix(line, []byte("tribe goes to "))
+	return bytes.HasPrefix(line, prefixTribeGoesTo)
 }
 
 func IsTribeMovement(line []byte) bool {
-	return bytes.HasPrefix(line, []byte("tribe movement:"))
+	return bytes.HasPrefix(line, prefixTribeMovement)
+}
+
+// IsNoReport determines if a line is the "no report" placeholder that
+// follows the header of a unit that didn't send anything for the turn.
+func IsNoReport(line []byte) bool {
+	return rxNoReport.Match(line)
 }
 
 // IsTurnHeader determines if a line represents a TribeNet turn header.
@@ -82,39 +107,76 @@ func IsTurnHeader(line []byte) bool {
 //
 // Returns true if the line matches any of these header patterns.
 func IsUnitHeader(line []byte) bool {
-	return rxTribeHeader.Match(line) || rxCourierHeader.Match(line) || rxElementHeader.Match(line) || rxFleetHeader.Match(line) || rxGarrisonHeader.Match(line)
+	_, ok := UnitKind(line)
+	return ok
+}
+
+// unitHeaders is the kind of unit for each unit header pattern.
+var unitHeaders = []struct {
+	kind string
+	rx   *regexp.Regexp
+}{
+	{kind: "tribe", rx: rxTribeHeader},
+	{kind: "courier", rx: rxCourierHeader},
+	{kind: "element", rx: rxElementHeader},
+	{kind: "fleet", rx: rxFleetHeader},
+	{kind: "garrison", rx: rxGarrisonHeader},
+}
+
+// UnitKind returns the kind of unit ("tribe", "courier", "element", "fleet", or "garrison")
+// for a line that IsUnitHeader accepts. Returns false if the line isn't a unit header.
+func UnitKind(line []byte) (kind string, ok bool) {
+	for _, header := range unitHeaders {
+		if header.rx.Match(line) {
+			return header.kind, true
+		}
+	}
+	return "", false
 }
 
 // IsUnitStatus determines if a line represents a TribeNet unit status line.
-// It checks for five different types of unit status lines:
+// It checks for six different types of unit status lines:
 //   - Tribe status
 //   - Courier status
+//   - Detachment status (units with a "d" suffix)
 //   - Element status
 //   - Fleet status
 //   - Garrison status
 //
 // Returns true if the line matches any of these status line patterns.
 func IsUnitStatus(line []byte) bool {
-	return rxTribeStatus.Match(line) || rxCourierStatus.Match(line) || rxElementStatus.Match(line) || rxFleetStatus.Match(line) || rxGarrisonStatus.Match(line)
+	return rxTribeStatus.Match(line) || rxCourierStatus.Match(line) || rxDetachStatus.Match(line) || rxElementStatus.Match(line) || rxFleetStatus.Match(line) || rxGarrisonStatus.Match(line)
 }
 
 // RemoveNonMappingLines filters an input slice of lines, keeping only:
 // - Unit headers
 // - Turn headers
-// - Movement lines
+// - Movement lines, including scout lines that start with "scout " or "scouts "
 // - Unit status lines
+// - "No report" placeholders, which mark a unit that didn't report this turn
 // Returns a new slice containing only the matching lines
+//
+// Lines are prefiltered on their first few bytes so that only the regular
+// expressions that could possibly match the line are run.
 func RemoveNonMappingLines(input [][]byte) [][]byte {
 	output := make([][]byte, 0, len(input))
 	for _, line := range input {
-		if IsUnitHeader(line) {
-			output = append(output, line)
-		} else if IsTurnHeader(line) {
-			output = append(output, line)
-		} else if IsMovementLine(line) {
-			output = append(output, line)
-		} else if IsUnitStatus(line) {
-			output = append(output, line)
+		if len(line) == 0 || !isMappingLineStart[line[0]] {
+			continue
+		} else if '0' <= line[0] && line[0] <= '9' {
+			// only status lines start with a digit
+			if IsUnitStatus(line) {
+				output = append(output, line)
+			}
+			continue
+		}
+		for _, mp := range mappingPrefixes {
+			if bytes.HasPrefix(line, mp.prefix) {
+				if mp.match(line) {
+					output = append(output, line)
+				}
+				break
+			}
 		}
 	}
 	return output
@@ -180,7 +242,12 @@ func ScrubEOL(input []byte) []byte {
 	if len(input) == 0 {
 		return input
 	}
-	output := bytes.NewBuffer(make([]byte, 0, len(input)))
+	return scrubEOL(make([]byte, 0, len(input)), input)
+}
+
+// scrubEOL appends the input to output with the EOLs converted and returns the result.
+// It lets callers reuse a scratch buffer for the output.
+func scrubEOL(output, input []byte) []byte {
 	for len(input) != 0 {
 		if input[0] == CR { // window or maybe classic mac
 			input = input[1:]
@@ -188,56 +255,107 @@ func ScrubEOL(input []byte) []byte {
 			if len(input) != 0 && input[0] == LF {
 				input = input[1:]
 			}
-			output.WriteByte(LF)
+			output = append(output, LF)
 			continue
 		}
-		output.WriteByte(input[0])
+		output = append(output, input[0])
 		input = input[1:]
 	}
-	return output.Bytes()
+	return output
+}
+
+// ExtractUnit returns the raw lines for a single unit, from the unit header
+// up to (but not including) the next unit header. The lines are returned
+// exactly as they appear in the input so that they can be shared.
+// Returns ErrUnitNotFound if there is no header for the unit.
+func ExtractUnit(input []byte, unitId string) ([]byte, error) {
+	var output [][]byte
+	inUnit := false
+	for _, line := range bytes.Split(input, []byte{'\n'}) {
+		if normalized := CompressSpaces(bytes.ToLower(bytes.TrimSpace(line))); IsUnitHeader(normalized) {
+			if inUnit {
+				break
+			}
+			match := rxHeaderUnitId.FindSubmatch(normalized)
+			inUnit = match != nil && string(match[2]) == unitId
+		}
+		if inUnit {
+			output = append(output, line)
+		}
+	}
+	if output == nil {
+		return nil, ErrUnitNotFound
+	}
+	return append(bytes.Join(RemoveTrailingBlankLines(output), []byte{'\n'}), '\n'), nil
 }
 
 type Section struct {
 	Id     int
+	Kind   string // kind of unit from the header ("courier", "element", "fleet", "garrison", or "tribe")
 	Header []byte
 	Turn   []byte
 	Moves  struct {
 		Movement []byte
 		Follows  []byte
 		GoesTo   []byte
-		Fleet    []byte
+		Fleet    [][]byte // a fleet may report movement for several phases
 		Scouts   [][]byte
 	}
 	Status []byte
+
+	// NoReport is set when the section has the "no report" placeholder.
+	NoReport bool
+
+	// Names maps the lower case names in the report to their original spelling.
+	// It is set by ParseText when the parser preserves case.
+	Names map[string]string
 }
 
 // SectionInput splits the input into lines and assigns lines to their own sections.
 // Each element in the input should get a single section
 // Each section should contain only movement lines, turn header, and unit header.
+// Scout lines are numbered 1 through DefaultMaxScouts. Use Parser.ParseText for a parser
+// created with WithMaxScouts.
 func SectionInput(input []byte) (sections []*Section) {
+	return sectionInput(input, DefaultMaxScouts)
+}
+
+// sectionInput is SectionInput for scouts numbered 1 through maxScouts.
+func sectionInput(input []byte, maxScouts int) (sections []*Section) {
 	var section *Section
-	for _, line := range bytes.Split(input, []byte{'\n'}) {
+	// a legend block at the end of the report isn't part of the last unit.
+	// the lines are walked in place rather than split up front since reports can be large.
+	for rest := input[:legendOffset(input)]; len(rest) != 0; {
+		line := rest
+		if n := bytes.IndexByte(rest, '\n'); n >= 0 {
+			// the capacity is capped like bytes.Split so that appending to a line can't overwrite the next one
+			line, rest = rest[:n:n], rest[n+1:]
+		} else {
+			rest = nil
+		}
 		if len(line) == 0 {
 			continue
-		} else if IsUnitHeader(line) {
-			section = &Section{Id: len(sections) + 1, Header: line}
+		} else if kind, ok := UnitKind(line); ok {
+			section = &Section{Id: len(sections) + 1, Kind: kind, Header: line}
 			sections = append(sections, section)
 		} else if section == nil {
 			continue
 		} else if IsFleetMovement(line) {
-			section.Moves.Fleet = line
+			section.Moves.Fleet = append(section.Moves.Fleet, line)
 		} else if IsTribeFollows(line) {
 			section.Moves.Follows = line
 		} else if IsTribeGoesTo(line) {
 			section.Moves.GoesTo = line
 		} else if IsTribeMovement(line) {
 			section.Moves.Movement = line
-		} else if IsScoutLine(line) {
+		} else if isScoutLine(line, maxScouts) {
 			section.Moves.Scouts = append(section.Moves.Scouts, line)
 		} else if IsTurnHeader(line) {
 			section.Turn = line
 		} else if IsUnitStatus(line) {
 			section.Status = line
+		} else if IsNoReport(line) {
+			section.NoReport = true
 		}
 	}
 	return sections
@@ -246,9 +364,71 @@ func SectionInput(input []byte) (sections []*Section) {
 var (
 	// pre-computed lookup table for delimiters
 	isSpaceDelimiter [256]bool
+
+	// pre-computed lookup table for the first byte of lines that are needed for mapping.
+	// headers, movement, and scout lines start with one of these letters and
+	// status lines start with a digit.
+	isMappingLineStart [256]bool
+
+	// the literal prefixes of the tribe movement lines.
+	prefixTribeFollows  = []byte("tribe follows ")
+	prefixTribeGoesTo   = []byte("tribe goes to ")
+	prefixTribeMovement = []byte("tribe movement:")
+
+	// the literal prefixes of the lines, other than status lines, that are needed for mapping.
+	// each prefix has the matcher that must accept a line with that prefix.
+	mappingPrefixes = []struct {
+		prefix []byte
+		match  func([]byte) bool
+	}{
+		{prefix: []byte("calm "), match: IsFleetMovement},
+		{prefix: []byte("courier "), match: rxCourierHeader.Match},
+		{prefix: []byte("current turn"), match: IsTurnHeader},
+		{prefix: []byte("element "), match: rxElementHeader.Match},
+		{prefix: []byte("fleet "), match: rxFleetHeader.Match},
+		{prefix: []byte("gale "), match: IsFleetMovement},
+		{prefix: []byte("garrison "), match: rxGarrisonHeader.Match},
+		{prefix: []byte("mild "), match: IsFleetMovement},
+		{prefix: []byte("no report"), match: IsNoReport},
+		{prefix: []byte("scout "), match: IsScoutLine},
+		{prefix: []byte("scouts "), match: IsScoutLine},
+		{prefix: []byte("strong "), match: IsFleetMovement},
+		{prefix: []byte("tribe "), match: func(line []byte) bool {
+			return rxTribeHeader.Match(line) || IsTribeMovement(line) || IsTribeFollows(line) || IsTribeGoesTo(line)
+		}},
+	}
 )
 
+// MappingPrefixes returns the literal prefixes of the mapping lines that
+// RemoveNonMappingLines keeps, in sorted order. The prefixes are lower case since
+// the parser works on lower-cased input. Scout lines start with "scout " or "scouts "
+// and a number. Status lines start with a unit id rather than a literal prefix, so they
+// are not included.
+//
+// Only the mapping lines are covered. The other lines that ToReport recognizes, such as
+// orders, sightings, "cannot move", weather, events, and the legend heading, are matched
+// by patterns rather than by prefix and are not included.
+func MappingPrefixes() []string {
+	var prefixes []string
+	for _, mp := range mappingPrefixes {
+		prefixes = append(prefixes, string(mp.prefix))
+	}
+	for _, prefix := range [][]byte{prefixTribeFollows, prefixTribeGoesTo, prefixTribeMovement} {
+		prefixes = append(prefixes, string(prefix))
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
 func init() {
+	// initialize the lookup table for the start of mapping lines
+	for _, ch := range []byte("0123456789") {
+		isMappingLineStart[ch] = true
+	}
+	for _, mp := range mappingPrefixes {
+		isMappingLineStart[mp.prefix[0]] = true
+	}
+
 	// initialize the lookup table for delimiters
 	for _, ch := range []byte{'\n', ',', '(', ')', '\\', ':'} {
 		isSpaceDelimiter[ch] = true
@@ -262,7 +442,12 @@ func CompressSpaces(input []byte) []byte {
 	if len(input) == 0 {
 		return input
 	}
-	output := bytes.NewBuffer(make([]byte, 0, len(input)))
+	return compressSpaces(make([]byte, 0, len(input)), input)
+}
+
+// compressSpaces appends the input to output with the spaces compressed and returns the result.
+// It lets callers reuse a scratch buffer for the output.
+func compressSpaces(output, input []byte) []byte {
 	prevCharWasDelimiter := false
 	for len(input) != 0 {
 		// if we find a space, advance the input to the end of the run of spaces
@@ -279,23 +464,117 @@ func CompressSpaces(input []byte) []byte {
 			if prevCharWasDelimiter || nextCharIsDelimiter {
 				continue
 			}
-			output.WriteByte(' ')
+			output = append(output, ' ')
 			continue
 		}
-		output.WriteByte(input[0])
+		output = append(output, input[0])
 		prevCharWasDelimiter = isSpaceDelimiter[input[0]]
 		input = input[1:]
 	}
-	return output.Bytes()
+	return output
+}
+
+// CompressSpacesPreserving is CompressSpaces, except that the bytes in each range
+// are copied unchanged. Each range is the start and end offset of a segment of the
+// input, like a settlement name, where the spacing is meaningful. The end is not
+// included in the segment. Ranges that are empty, overlap an earlier range, or
+// are out of bounds are ignored.
+// Example: with the range of the quoted name, `tribe  0123, "west ( harbor )"`
+// becomes `tribe 0123,"west ( harbor )"`
+func CompressSpacesPreserving(input []byte, ranges [][2]int) []byte {
+	if len(input) == 0 {
+		return input
+	}
+	ranges = slices.Clone(ranges)
+	slices.SortFunc(ranges, func(a, b [2]int) int { return a[0] - b[0] })
+	output := make([]byte, 0, len(input))
+	prevCharWasDelimiter := false
+	for pos := 0; pos < len(input); {
+		// skip past ranges that can't be used
+		for len(ranges) != 0 && (ranges[0][0] < pos || ranges[0][1] <= ranges[0][0] || ranges[0][1] > len(input)) {
+			ranges = ranges[1:]
+		}
+		// end is where the next preserved range starts
+		end := len(input)
+		if len(ranges) != 0 {
+			end = ranges[0][0]
+		}
+		if pos == end {
+			output = append(output, input[ranges[0][0]:ranges[0][1]]...)
+			pos = ranges[0][1]
+			prevCharWasDelimiter = isSpaceDelimiter[input[pos-1]]
+			ranges = ranges[1:]
+			continue
+		}
+		if input[pos] == ' ' || input[pos] == '\t' { // found a space
+			for pos < end && (input[pos] == ' ' || input[pos] == '\t') {
+				pos++
+			}
+			// a preserved range is not a delimiter, so the space before it is kept
+			nextCharIsDelimiter := pos == len(input) || (pos < end && isSpaceDelimiter[input[pos]])
+			if prevCharWasDelimiter || nextCharIsDelimiter {
+				continue
+			}
+			output = append(output, ' ')
+			continue
+		}
+		output = append(output, input[pos])
+		prevCharWasDelimiter = isSpaceDelimiter[input[pos]]
+		pos++
+	}
+	return output
+}
+
+// QuotedRanges returns the ranges of the segments of the input that are enclosed
+// in double quotes, including the quotes. An unmatched quote is ignored.
+// The result can be passed to CompressSpacesPreserving.
+func QuotedRanges(input []byte) (ranges [][2]int) {
+	for start := 0; start < len(input); {
+		open := bytes.IndexByte(input[start:], '"')
+		if open == -1 {
+			break
+		}
+		open += start
+		end := bytes.IndexByte(input[open+1:], '"')
+		if end == -1 {
+			break
+		}
+		end += open + 2
+		ranges = append(ranges, [2]int{open, end})
+		start = end
+	}
+	return ranges
+}
+
+// unquote returns the text without the quotes around it, like a quoted
+// settlement name ("dowdy holler"). Straight and curly quotes are removed,
+// in any combination, but only when the text both starts and ends with one.
+// Quotes inside the text, like the apostrophe in "o'neal's ford", are kept.
+func unquote(text string) string {
+	first, n := utf8.DecodeRuneInString(text)
+	last, m := utf8.DecodeLastRuneInString(text)
+	if n+m > len(text) || !isQuote(first) || !isQuote(last) {
+		return text
+	}
+	return strings.TrimSpace(text[n : len(text)-m])
+}
+
+// isQuote returns true if the rune is a straight or curly quotation mark.
+func isQuote(r rune) bool {
+	switch r {
+	case '"', '\'', '\u201c', '\u201d', '\u2018', '\u2019':
+		return true
+	}
+	return false
 }
 
 var (
 	reBackslashDash = regexp.MustCompile(`\\+-+ *`)
 
 	reBackslashComma = regexp.MustCompile(`\\+,+`)
-	reBackslashUnit  = regexp.MustCompile(`\\+(\d{4}(?:[cefg]\d)?)`)
+	reBackslashUnit  = regexp.MustCompile(`\\+(\d{4}(?:[cdefg]\d+)?)`)
 	reCommaBackslash = regexp.MustCompile(`,+\\`)
-	reDirectionUnit  = regexp.MustCompile(`\b(ne|se|sw|nw|n|s) (\d{4}(?:[cefg]\d)?)`)
+	reDirectionUnit  = regexp.MustCompile(`\b(ne|se|sw|nw|n|s) (\d{4}(?:[cdefg]\d+)?)`)
 
 	reRunOfBackslashes = regexp.MustCompile(`\\\\+`)
 	reRunOfComma       = regexp.MustCompile(`,,+`)
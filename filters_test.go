@@ -3,7 +3,13 @@
 package tndocx_test
 
 import (
+	"bytes"
 	"github.com/playbymail/tndocx"
+	"github.com/playbymail/tndocx/docx"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -64,3 +70,420 @@ func TestCompressSpaces(t *testing.T) {
 		})
 	}
 }
+
+func TestCompressSpacesPreserving(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "no ranges",
+			input:    "tribe   0123,   status:  active  ( good )",
+			expected: "tribe 0123,status:active(good)",
+		},
+		{
+			name:     "quoted settlement",
+			input:    `0123 status:prairie,  "west  harbor ( port )" ,o ne`,
+			expected: `0123 status:prairie,"west  harbor ( port )",o ne`,
+		},
+		{
+			name:     "space before quote is kept",
+			input:    `tribe   goes to qq 0707 "big stone"`,
+			expected: `tribe goes to qq 0707 "big stone"`,
+		},
+		{
+			name:     "unmatched quote",
+			input:    `tribe   0123, "big  stone`,
+			expected: `tribe 0123,"big stone`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := []byte(tt.input)
+			got := string(tndocx.CompressSpacesPreserving(input, tndocx.QuotedRanges(input)))
+			if got != tt.expected {
+				t.Errorf("CompressSpacesPreserving() = %q, want %q", got, tt.expected)
+			}
+			if ranges := tndocx.QuotedRanges(input); ranges == nil {
+				if want := string(tndocx.CompressSpaces(input)); got != want {
+					t.Errorf("CompressSpacesPreserving() = %q, want CompressSpaces() = %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractUnit(t *testing.T) {
+	input := []byte(`Tribe 0138, , Current Hex = ## 0709, (Previous Hex = ## 0709)
+Current Turn 900-04 (#4), Summer, FINE
+Tribe Movement: Move N-PR
+0138 Status: PRAIRIE, 0138
+
+Element 0138e1, Scouts, Current Hex = ## 0708, (Previous Hex = ## 0709)
+Tribe Movement: Move N-PR
+0138e1 Status: PRAIRIE, 0138e1
+
+Courier 0138c1, , Current Hex = ## 0709, (Previous Hex = ## 0709)
+0138c1 Status: PRAIRIE, 0138c1
+`)
+	tests := []struct {
+		name     string
+		unitId   string
+		expected string
+	}{
+		{name: "first unit", unitId: "0138", expected: "Tribe 0138, , Current Hex = ## 0709, (Previous Hex = ## 0709)\nCurrent Turn 900-04 (#4), Summer, FINE\nTribe Movement: Move N-PR\n0138 Status: PRAIRIE, 0138\n"},
+		{name: "middle unit", unitId: "0138e1", expected: "Element 0138e1, Scouts, Current Hex = ## 0708, (Previous Hex = ## 0709)\nTribe Movement: Move N-PR\n0138e1 Status: PRAIRIE, 0138e1\n"},
+		{name: "last unit", unitId: "0138c1", expected: "Courier 0138c1, , Current Hex = ## 0709, (Previous Hex = ## 0709)\n0138c1 Status: PRAIRIE, 0138c1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tndocx.ExtractUnit(input, tt.unitId)
+			if err != nil {
+				t.Fatalf("ExtractUnit() error = %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("ExtractUnit() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	if _, err := tndocx.ExtractUnit(input, "0138f1"); err != tndocx.ErrUnitNotFound {
+		t.Errorf("ExtractUnit() error = %v, want %v", err, tndocx.ErrUnitNotFound)
+	}
+}
+
+// mappingFixture is a small report with both mapping and non-mapping lines.
+var mappingFixture = [][]byte{
+	[]byte("tribe 0138,,current hex = ## 0709,(previous hex = ## 0709)"),
+	[]byte("current turn 900-04(#4),summer,fine"),
+	[]byte("tribe movement:move ne-pr\\n-gh"),
+	[]byte("humans"),
+	[]byte("people 1200,warriors 250,actives 300"),
+	[]byte("scout 1:scout n-pr\\nothing of interest found"),
+	[]byte("tribe follows 0138e1"),
+	[]byte("tribe goes to qq 0707"),
+	[]byte("calm ne fleet movement:move ne-o"),
+	[]byte("0138 status:prairie,0138"),
+	[]byte("2 horses,5 grain"),
+	[]byte("element 0138e1,,current hex = ## 0709,(previous hex = ## 0709)"),
+	[]byte("final turn transfers"),
+	[]byte("0138e1 status:prairie,0138e1"),
+	[]byte(""),
+	[]byte("skills:hunting 1,herding 2"),
+}
+
+func TestRemoveNonMappingLines(t *testing.T) {
+	expected := []string{
+		"tribe 0138,,current hex = ## 0709,(previous hex = ## 0709)",
+		"current turn 900-04(#4),summer,fine",
+		"tribe movement:move ne-pr\\n-gh",
+		"scout 1:scout n-pr\\nothing of interest found",
+		"tribe follows 0138e1",
+		"tribe goes to qq 0707",
+		"calm ne fleet movement:move ne-o",
+		"0138 status:prairie,0138",
+		"element 0138e1,,current hex = ## 0709,(previous hex = ## 0709)",
+		"0138e1 status:prairie,0138e1",
+	}
+	got := tndocx.RemoveNonMappingLines(mappingFixture)
+	if len(got) != len(expected) {
+		t.Fatalf("len(RemoveNonMappingLines()) = %d, want %d", len(got), len(expected))
+	}
+	for i := range expected {
+		if string(got[i]) != expected[i] {
+			t.Errorf("line %d: got %q, want %q", i+1, got[i], expected[i])
+		}
+	}
+}
+
+func BenchmarkRemoveNonMappingLines(b *testing.B) {
+	var input [][]byte
+	for i := 0; i < 250; i++ {
+		input = append(input, mappingFixture...)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tndocx.RemoveNonMappingLines(input)
+	}
+}
+
+// equivalenceInputs returns the reports that are used to check that the optimized
+// filters give the same output as the straightforward versions. They are the
+// mapping fixture, the legacy Word document in testdata, and a report with lines
+// that look like mapping lines but aren't.
+func equivalenceInputs(t *testing.T) map[string][]byte {
+	t.Helper()
+	doc, err := os.ReadFile("testdata/0900-04.0138.report.doc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := docx.ReadDoc(doc)
+	if err != nil {
+		t.Fatalf("ReadDoc() error = %v", err)
+	}
+	return map[string][]byte{
+		"mapping fixture": bytes.Join(mappingFixture, []byte{'\n'}),
+		"word document":   tndocx.CompressSpaces(text),
+		"near misses": []byte(strings.Join([]string{
+			"tribe 0138,,current hex = qq 0709,(previous hex = qq 0708)",
+			"tribe 0138 is hungry",
+			"tribes movement:move n-pr",
+			"tribe movement:move n-pr\\ne-gh",
+			"Tribe Movement:Move N-PR",
+			"current turn #5",
+			"current turnip harvest",
+			"scout 9:scout n-pr",
+			"scout 1:scout n-pr",
+			"scouts 2:scout s-pr",
+			"no report",
+			"no report.",
+			"no reporting",
+			"gale nw fleet movement:move nw-o",
+			"gale warnings",
+			"strong winds",
+			"2 horses,5 grain",
+			"0138 status:prairie,0138",
+			"0138e1 status:prairie,0138e1",
+			"fleet 0138f1,,current hex = qq 0709,(previous hex = qq 0708)",
+			"fleet 0138,,current hex = qq 0709,(previous hex = qq 0708)",
+			"garrison 0138g1,,current hex = n/a,(previous hex = n/a)",
+			"courier 0138c12,,current hex = qq 0709,(previous hex = qq 0708)",
+			"element 0138e1",
+			"",
+			"legend",
+			"pr = prairie",
+		}, "\n")),
+	}
+}
+
+// TestRemoveNonMappingLinesEquivalence checks that the prefiltered RemoveNonMappingLines
+// keeps the same lines as checking every line with the exported predicates.
+func TestRemoveNonMappingLinesEquivalence(t *testing.T) {
+	reference := func(input [][]byte) (output [][]byte) {
+		for _, line := range input {
+			if tndocx.IsUnitHeader(line) || tndocx.IsTurnHeader(line) || tndocx.IsMovementLine(line) || tndocx.IsUnitStatus(line) || tndocx.IsNoReport(line) {
+				output = append(output, line)
+			}
+		}
+		return output
+	}
+	for name, input := range equivalenceInputs(t) {
+		lines := bytes.Split(input, []byte{'\n'})
+		got, want := tndocx.RemoveNonMappingLines(lines), reference(lines)
+		if len(want) == 0 {
+			t.Fatalf("%s: no mapping lines in the fixture", name)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: RemoveNonMappingLines() = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestSectionInputKind(t *testing.T) {
+	input := []byte(`courier 0138c1,,current hex = ## 0709,(previous hex = ## 0709)
+element 0138e1,,current hex = ## 0709,(previous hex = ## 0709)
+fleet 0138f1,,current hex = ## 0709,(previous hex = ## 0709)
+garrison 0138g1,,current hex = ## 0709,(previous hex = ## 0709)
+tribe 0138,,current hex = ## 0709,(previous hex = ## 0709)
+`)
+	expected := []string{"courier", "element", "fleet", "garrison", "tribe"}
+	sections := tndocx.SectionInput(input)
+	if len(sections) != len(expected) {
+		t.Fatalf("len(SectionInput()) = %d, want %d", len(sections), len(expected))
+	}
+	for i, kind := range expected {
+		if sections[i].Kind != kind {
+			t.Errorf("section %d: Kind = %q, want %q", sections[i].Id, sections[i].Kind, kind)
+		}
+	}
+}
+
+func TestSectionInputLegend(t *testing.T) {
+	report := "tribe 0138,,current hex = ## 0709,(previous hex = ## 0709)\n" +
+		"tribe movement:move ne-pr\n" +
+		"0138 status:prairie,0138\n"
+	for _, tc := range []struct {
+		name   string
+		legend string
+	}{
+		{name: "no legend"},
+		{name: "legend", legend: "\nlegend\npr = prairie\ngh = grassy hills\n"},
+		{name: "no heading", legend: "pr = prairie\n\n"},
+		{name: "no trailing newline", legend: "map key:\npr = prairie"},
+	} {
+		sections := tndocx.SectionInput([]byte(report + tc.legend))
+		if len(sections) != 1 {
+			t.Errorf("%s: len(SectionInput()) = %d, want 1", tc.name, len(sections))
+			continue
+		}
+		section := sections[0]
+		if string(section.Moves.Movement) != "tribe movement:move ne-pr" || string(section.Status) != "0138 status:prairie,0138" {
+			t.Errorf("%s: Movement, Status = %q, %q", tc.name, section.Moves.Movement, section.Status)
+		}
+	}
+}
+
+// splitSectionInput is SectionInput as it was before it walked the lines in place:
+// the input is split up front and the legend block is found by working back over the lines.
+// The legend patterns are copied from legend.go.
+func splitSectionInput(input []byte) (sections []*tndocx.Section) {
+	rxLegendHeading := regexp.MustCompile(`^(?:map )?(?:legend|key)(?: to abbreviations)?:?$`)
+	rxLegendLine := regexp.MustCompile(`^([^=,]{1,20}?) ?= ?([^=]+)$`)
+	lines := bytes.Split(input, []byte{'\n'})
+	legendStart, found := len(lines), false
+	for legendStart > 0 {
+		line := bytes.TrimSpace(lines[legendStart-1])
+		if len(line) != 0 && !rxLegendLine.Match(line) {
+			break
+		}
+		legendStart, found = legendStart-1, found || len(line) != 0
+	}
+	if legendStart > 0 && rxLegendHeading.Match(bytes.TrimSpace(lines[legendStart-1])) {
+		legendStart--
+	}
+	if !found {
+		legendStart = len(lines)
+	}
+	var section *tndocx.Section
+	for _, line := range lines[:legendStart] {
+		if len(line) == 0 {
+			continue
+		} else if kind, ok := tndocx.UnitKind(line); ok {
+			section = &tndocx.Section{Id: len(sections) + 1, Kind: kind, Header: line}
+			sections = append(sections, section)
+		} else if section == nil {
+			continue
+		} else if tndocx.IsFleetMovement(line) {
+			section.Moves.Fleet = append(section.Moves.Fleet, line)
+		} else if tndocx.IsTribeFollows(line) {
+			section.Moves.Follows = line
+		} else if tndocx.IsTribeGoesTo(line) {
+			section.Moves.GoesTo = line
+		} else if tndocx.IsTribeMovement(line) {
+			section.Moves.Movement = line
+		} else if tndocx.IsScoutLine(line) {
+			section.Moves.Scouts = append(section.Moves.Scouts, line)
+		} else if tndocx.IsTurnHeader(line) {
+			section.Turn = line
+		} else if tndocx.IsUnitStatus(line) {
+			section.Status = line
+		} else if tndocx.IsNoReport(line) {
+			section.NoReport = true
+		}
+	}
+	return sections
+}
+
+// TestSectionInputEquivalence checks that walking the lines in place gives the same
+// sections as splitting the input up front.
+func TestSectionInputEquivalence(t *testing.T) {
+	inputs := equivalenceInputs(t)
+	inputs["legend"] = []byte("tribe 0138,,current hex = ## 0709,(previous hex = ## 0709)\n" +
+		"tribe movement:move ne-pr\n0138 status:prairie,0138\n\nmap key:\npr = prairie\n\n")
+	inputs["no trailing newline"] = []byte("tribe 0138,,current hex = ## 0709,(previous hex = ## 0709)\ntribe movement:move ne-pr")
+	for name, input := range inputs {
+		got, want := tndocx.SectionInput(input), splitSectionInput(input)
+		if len(want) == 0 {
+			t.Fatalf("%s: no sections in the fixture", name)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: SectionInput() = %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func BenchmarkSectionInput(b *testing.B) {
+	input := bytes.Join(mappingFixture, []byte{'\n'})
+	input = bytes.Repeat(append(input, '\n'), 250)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tndocx.SectionInput(input)
+	}
+}
+
+func TestMappingPrefixes(t *testing.T) {
+	prefixes := map[string]bool{}
+	for _, prefix := range tndocx.MappingPrefixes() {
+		prefixes[prefix] = true
+	}
+	for _, want := range []string{
+		"courier ", "current turn", "element ", "fleet ", "garrison ", "scout ",
+		"tribe ", "tribe follows ", "tribe goes to ", "tribe movement:",
+	} {
+		if !prefixes[want] {
+			t.Errorf("MappingPrefixes() missing %q", want)
+		}
+	}
+}
+
+func TestTwoDigitUnitSuffixes(t *testing.T) {
+	for _, tt := range []struct {
+		kind, id string
+	}{
+		{kind: "courier", id: "0138c12"},
+		{kind: "element", id: "0138e10"},
+		{kind: "fleet", id: "0138f11"},
+		{kind: "garrison", id: "0138g10"},
+	} {
+		t.Run(tt.kind, func(t *testing.T) {
+			header := []byte(tt.kind + " " + tt.id + ",,current hex = qq 0709,(previous hex = qq 0709)")
+			if !tndocx.IsUnitHeader(header) {
+				t.Errorf("IsUnitHeader(%q) = false, want true", header)
+			}
+			status := []byte(tt.id + " status:prairie,o ne," + tt.id)
+			if !tndocx.IsUnitStatus(status) {
+				t.Errorf("IsUnitStatus(%q) = false, want true", status)
+			}
+			r := tndocx.ToReport("0900-04.0138.report.txt", [][]byte{header, []byte("tribe follows " + tt.id), status})
+			unit, ok := r.Units[tt.id]
+			if !ok {
+				t.Fatalf("ToReport() did not return unit %s", tt.id)
+			}
+			if unit.Kind != tt.kind {
+				t.Errorf("Kind = %q, want %q", unit.Kind, tt.kind)
+			}
+			if len(unit.Moves) != 1 || unit.Moves[0].Follows != tt.id {
+				t.Errorf("Moves = %+v, want follows %s", unit.Moves, tt.id)
+			}
+			if unit.Status == nil || unit.Status.Terrain != "prairie" {
+				t.Errorf("Status = %+v, want prairie", unit.Status)
+			}
+			if r.ClanId != "0138" {
+				t.Errorf("ClanId = %q, want %q", r.ClanId, "0138")
+			}
+		})
+	}
+}
+
+func TestDetachmentStatus(t *testing.T) {
+	status := []byte("0987d1 status:prairie,o ne,0987d1")
+	if !tndocx.IsUnitStatus(status) {
+		t.Errorf("IsUnitStatus(%q) = false, want true", status)
+	}
+	if got := tndocx.RemoveNonMappingLines([][]byte{status}); len(got) != 1 {
+		t.Errorf("RemoveNonMappingLines() dropped %q", status)
+	}
+}
+
+func TestUnitKind(t *testing.T) {
+	for _, tc := range []struct {
+		line string
+		kind string
+		ok   bool
+	}{
+		{line: "tribe 0138,,current hex = qq 0709,(previous hex = qq 0708)", kind: "tribe", ok: true},
+		{line: "courier 0138c1,,current hex = qq 0709,(previous hex = qq 0708)", kind: "courier", ok: true},
+		{line: "element 0138e12,,current hex = qq 0709,(previous hex = qq 0708)", kind: "element", ok: true},
+		{line: "fleet 0138f1,,current hex = qq 0709,(previous hex = qq 0708)", kind: "fleet", ok: true},
+		{line: "garrison 0138g1,,current hex = qq 0709,(previous hex = qq 0708)", kind: "garrison", ok: true},
+		{line: "0138 status:prairie,0138"},
+		{line: "tribe movement:move n-pr"},
+	} {
+		kind, ok := tndocx.UnitKind([]byte(tc.line))
+		if kind != tc.kind || ok != tc.ok {
+			t.Errorf("UnitKind(%q) = %q, %v, want %q, %v", tc.line, kind, ok, tc.kind, tc.ok)
+		}
+	}
+}
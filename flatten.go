@@ -0,0 +1,103 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+// UnitRow is a unit from a report, flattened for loading into a database.
+type UnitRow struct {
+	TurnId     string
+	UnitId     string
+	Kind       string
+	Name       string
+	From       string
+	To         string
+	Terrain    string
+	Status     string
+	Orders     string
+	Still      bool
+	Population int
+	Warriors   int
+}
+
+// StepRow is a step from a unit's movement, flattened for loading into a database.
+// Seq is the position of the step in the unit's moves, starting at 1.
+type StepRow struct {
+	TurnId     string
+	UnitId     string
+	Seq        int
+	Step       string
+	Direction  string
+	Terrain    string
+	Follows    string
+	GoesTo     string
+	Settlement string
+	Still      bool
+}
+
+// ScoutRow is a step from a scout patrol, flattened for loading into a database.
+// Seq is the position of the step in the patrol, starting at 1.
+type ScoutRow struct {
+	TurnId    string
+	UnitId    string
+	ScoutId   string
+	Seq       int
+	Step      string
+	Outcome   string
+	Direction string
+	Terrain   string
+	Reason    string
+}
+
+// FlattenReport returns the units, steps, and scout steps in the report as rows
+// suitable for a batch insert. Every row carries the turn id and unit id so that
+// the row sets can be joined. Units are returned in order of their id.
+func FlattenReport(r *Report) (units []UnitRow, steps []StepRow, scouts []ScoutRow) {
+	for _, id := range r.sortedUnitIds() {
+		unit := r.Units[id]
+		row := UnitRow{
+			TurnId:     r.TurnId,
+			UnitId:     unit.Id,
+			Kind:       unit.Kind,
+			Name:       unit.Name,
+			From:       unit.From,
+			To:         unit.To,
+			Orders:     unit.Orders,
+			Still:      unit.Still,
+			Population: unit.Population,
+			Warriors:   unit.Warriors,
+		}
+		if unit.Status != nil {
+			row.Terrain, row.Status = unit.Status.Terrain, unit.Status.Raw
+		}
+		units = append(units, row)
+		for n, step := range unit.Moves {
+			steps = append(steps, StepRow{
+				TurnId:     r.TurnId,
+				UnitId:     unit.Id,
+				Seq:        n + 1,
+				Step:       step.Step,
+				Direction:  step.Direction,
+				Terrain:    step.Terrain,
+				Follows:    step.Follows,
+				GoesTo:     step.GoesTo,
+				Settlement: step.Settlement,
+				Still:      step.Still,
+			})
+		}
+		for _, scout := range unit.Scouts {
+			for n, step := range scout.Steps {
+				scouts = append(scouts, ScoutRow{
+					TurnId:    r.TurnId,
+					UnitId:    unit.Id,
+					ScoutId:   scout.Id,
+					Seq:       n + 1,
+					Step:      step.Step,
+					Outcome:   string(step.Outcome),
+					Direction: step.Direction,
+					Terrain:   step.Terrain,
+					Reason:    step.Reason,
+				})
+			}
+		}
+	}
+	return units, steps, scouts
+}
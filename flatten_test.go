@@ -0,0 +1,43 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"github.com/playbymail/tndocx"
+	"reflect"
+	"testing"
+)
+
+func TestFlattenReport(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"Current Turn 900-04 (#4), Summer, FINE",
+		`Tribe Movement: Move N-PR\NE-GH`,
+		"Scout 1:Scout N-GH,  Nothing of interest found",
+		"0138 Status: PRAIRIE, O NE, 0138",
+		"Courier 0138c1, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		"Tribe Follows 0138",
+	)
+	units, steps, scouts := tndocx.FlattenReport(r)
+	wantUnits := []tndocx.UnitRow{
+		{TurnId: "0900-04", UnitId: "0138", Kind: "tribe", From: "qq 0708", To: "qq 0709", Terrain: "prairie", Status: "prairie,o ne,0138"},
+		{TurnId: "0900-04", UnitId: "0138c1", Kind: "courier", From: "qq 0709", To: "qq 0709"},
+	}
+	if !reflect.DeepEqual(units, wantUnits) {
+		t.Errorf("units = %+v, want %+v", units, wantUnits)
+	}
+	wantSteps := []tndocx.StepRow{
+		{TurnId: "0900-04", UnitId: "0138", Seq: 1, Step: "n-pr", Direction: "n", Terrain: "pr"},
+		{TurnId: "0900-04", UnitId: "0138", Seq: 2, Step: "ne-gh", Direction: "ne", Terrain: "gh"},
+		{TurnId: "0900-04", UnitId: "0138c1", Seq: 1, Follows: "0138"},
+	}
+	if !reflect.DeepEqual(steps, wantSteps) {
+		t.Errorf("steps = %+v, want %+v", steps, wantSteps)
+	}
+	wantScouts := []tndocx.ScoutRow{
+		{TurnId: "0900-04", UnitId: "0138", ScoutId: "1", Seq: 1, Step: "n-gh,nothing of interest found", Outcome: "empty", Direction: "n", Terrain: "gh"},
+	}
+	if !reflect.DeepEqual(scouts, wantScouts) {
+		t.Errorf("scouts = %+v, want %+v", scouts, wantScouts)
+	}
+}
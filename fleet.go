@@ -0,0 +1,128 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Fleet is the manifest that a fleet reports in its status line.
+// Carrying is the units on board the fleet. Units that are in the same hex
+// but not on board are not included; they stay in the unit list of the status.
+type Fleet struct {
+	Carrying []string `json:"carrying,omitempty"`
+	Cargo    []string `json:"cargo,omitempty"`
+}
+
+var (
+	// rxFleetCarrying captures the units on board a fleet from a status segment.
+	// these look like:
+	// - carrying 0138 0138e1
+	// - passengers 0138c1
+	rxFleetCarrying = regexp.MustCompile(`^(?:carrying|passengers) ((?:\d{4}(?:[cdefg]\d+)? ?)+)$`)
+
+	// rxFleetCargo captures the goods in the hold of a fleet from a status segment.
+	// these look like:
+	// - cargo 100 grain 20 wood
+	// - cargo 5 iron ore
+	rxFleetCargo = regexp.MustCompile(`^cargo (.+)$`)
+
+	// rxFleetObservation captures the contents of the parenthesized observations on a fleet movement step.
+	// the closing parenthesis may be missing when the line was cut short.
+	// these look like:
+	// - (ne do,n o)
+	// - (ne o,n/ne lcm,)
+	rxFleetObservation = regexp.MustCompile(`^\((.*?)\)?$`)
+
+	// rxFleetSighting captures the direction and terrain of one observation from a fleet.
+	// hexes further away are given as a path of directions from the fleet's hex.
+	// these look like:
+	// - ne do
+	// - n/ne lcm
+	rxFleetSighting = regexp.MustCompile(`^((?:ne|se|sw|nw|n|s)(?:/(?:ne|se|sw|nw|n|s))*) ([a-z][a-z ]*)$`)
+)
+
+// fleetObservations returns the terrains from the parenthesized observations on a fleet movement step.
+// Each observation is a direction followed by a terrain ("ne do") or a terrain followed by one or more
+// directions ("lcm ne n"), like on a status line. The directions for a terrain may be separated by
+// commas ("lcm ne,n"), so bare directions are added to the preceding terrain. A path of directions
+// ("n/ne o") is a hex further away; the Direction is the whole path and the Distance is its length.
+// Empty entries, like the one left by a trailing comma, are ignored. Commas inside nested
+// parentheses don't split an entry, and the nested text, like the settlement in
+// "ne pr (arcadia, port)", is ignored.
+func (p *Parser) fleetObservations(text string) (observations []Observation) {
+	match := rxFleetObservation.FindStringSubmatch(strings.TrimSpace(text))
+	if match == nil {
+		return nil
+	}
+	terrain := "" // the terrain for bare directions that follow a terrain-first entry
+	for _, segment := range splitOutsideParens(match[1]) {
+		fields := strings.Fields(removeParens(segment))
+		if len(fields) == 0 {
+			continue
+		} else if m := rxFleetSighting.FindStringSubmatch(strings.Join(fields, " ")); m != nil {
+			terrain = ""
+			observations = append(observations, Observation{
+				Direction: m[1],
+				Terrain:   p.terrainCodeOrName(m[2]),
+				Distance:  strings.Count(m[1], "/") + 1,
+			})
+		} else if nt, ok := p.neighboringTerrain(fields); ok {
+			terrain = nt.Terrain
+			for _, direction := range nt.Directions {
+				observations = append(observations, Observation{Direction: direction, Terrain: terrain, Distance: 1})
+			}
+		} else if terrain != "" && isDirectionList(fields) {
+			for _, direction := range fields {
+				observations = append(observations, Observation{Direction: direction, Terrain: terrain, Distance: 1})
+			}
+		} else {
+			terrain = ""
+		}
+	}
+	return observations
+}
+
+// terrainCodeOrName returns the code for a terrain name from the vocabulary.
+// Codes and names that aren't in the vocabulary are returned unchanged.
+func (p *Parser) terrainCodeOrName(name string) string {
+	if _, ok := p.vocabulary.Terrains[name]; ok {
+		return name
+	} else if code, ok := p.vocabulary.TerrainCode(name); ok {
+		return code
+	}
+	return name
+}
+
+// fleetManifest returns the manifest from the text of a fleet's status line.
+// Each cargo item starts with a quantity, so "cargo 100 grain 20 wood" is
+// two items, "100 grain" and "20 wood".
+// Returns nil if the status doesn't include a manifest.
+func fleetManifest(raw string) *Fleet {
+	var fleet *Fleet
+	for _, segment := range strings.Split(raw, ",") {
+		segment = strings.TrimSpace(segment)
+		if match := rxFleetCarrying.FindStringSubmatch(segment); match != nil {
+			if fleet == nil {
+				fleet = &Fleet{}
+			}
+			fleet.Carrying = append(fleet.Carrying, strings.Fields(match[1])...)
+		} else if match := rxFleetCargo.FindStringSubmatch(segment); match != nil {
+			if fleet == nil {
+				fleet = &Fleet{}
+			}
+			var item []string
+			for _, field := range strings.Fields(match[1]) {
+				if len(item) != 0 && field[0] >= '0' && field[0] <= '9' {
+					fleet.Cargo, item = append(fleet.Cargo, strings.Join(item, " ")), nil
+				}
+				item = append(item, field)
+			}
+			if len(item) != 0 {
+				fleet.Cargo = append(fleet.Cargo, strings.Join(item, " "))
+			}
+		}
+	}
+	return fleet
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"slices"
+	"strings"
+)
+
+// ResolveFollows sets the current hex of each unit that follows another unit when the
+// follower's own current hex is obscured ("## 0709") or unknown. A follower ends the turn
+// in the same hex as its leader, so the hex is copied from the leader. If the leader's hex
+// is also obscured or unknown and the leader follows a unit, the chain is walked until a
+// unit with a known hex is found. Leaders that aren't in the report end the walk.
+//
+// An unknown hex is replaced by an obscured one, but an obscured hex is only replaced
+// by a hex with a known grid. A chain that loops back on itself, like 0138e1 following
+// 0138e2 following 0138e1, is added to the report's warnings once and left unresolved.
+func (r *Report) ResolveFollows() {
+	leaders := map[string]string{}
+	for id, unit := range r.Units {
+		for _, step := range unit.Moves {
+			if step.Kind == StepFollows && step.Follows != "" {
+				leaders[id] = step.Follows
+			}
+		}
+	}
+	warned := map[string]bool{}
+	for _, id := range r.sortedUnitIds() {
+		follower := r.Units[id]
+		if leaders[id] == "" || !followerNeedsHex(follower.To) {
+			continue
+		}
+		chain := []string{id}
+		for leaderId := leaders[id]; leaderId != ""; leaderId = leaders[leaderId] {
+			if n := slices.Index(chain, leaderId); n >= 0 {
+				if cycle := chain[n:]; !warned[leaderId] {
+					for _, unitId := range cycle {
+						warned[unitId] = true
+					}
+					r.Warnings = append(r.Warnings, Warning{
+						UnitId:  leaderId,
+						Message: "follows cycle: " + strings.Join(append(cycle, leaderId), " -> "),
+					})
+				}
+				break
+			}
+			leader, ok := r.Units[leaderId]
+			if !ok {
+				break
+			} else if hex, ok := parseHexCoordinate(leader.To); ok && (!hex.Obscured || !isKnownHex(follower.To)) {
+				follower.To = leader.To
+				follower.setHexes()
+				if !hex.Obscured {
+					break
+				}
+			}
+			chain = append(chain, leaderId)
+		}
+	}
+}
+
+// followerNeedsHex reports whether a follower's current hex is obscured or unknown.
+func followerNeedsHex(hex string) bool {
+	h, ok := parseHexCoordinate(hex)
+	return !ok || h.Obscured
+}
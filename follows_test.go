@@ -0,0 +1,50 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"github.com/playbymail/tndocx"
+	"reflect"
+	"testing"
+)
+
+func TestReportResolveFollows(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"Tribe Movement: Move S-PR",
+		"Element 0138e1, , Current Hex = ## 0709, (Previous Hex = ## 0708)",
+		"Tribe Follows 0138",
+		"Element 0138e2, , Current Hex = N/A, (Previous Hex = N/A)",
+		"Tribe Follows 0138e1",
+		"Element 0138e3, , Current Hex = ## 0712, (Previous Hex = ## 0712)",
+		"Tribe Follows 0138e4",
+		"Element 0138e4, , Current Hex = ## 0712, (Previous Hex = ## 0712)",
+		"Tribe Follows 0138e3",
+		"Element 0138e5, , Current Hex = ## 0101, (Previous Hex = ## 0101)",
+		"Tribe Follows 0987",
+		"Element 0138e6, , Current Hex = RR 0101, (Previous Hex = RR 0101)",
+		"Tribe Follows 0138",
+	)
+	r.ResolveFollows()
+	for id, want := range map[string]string{
+		"0138e1": "qq 0709", // obscured hex resolved from the leader
+		"0138e2": "qq 0709", // resolved through 0138e1
+		"0138e3": "## 0712", // cycle, left unresolved
+		"0138e4": "## 0712",
+		"0138e5": "## 0101", // leader isn't in the report
+		"0138e6": "rr 0101", // known hex isn't replaced
+	} {
+		if got := r.Units[id].To; got != want {
+			t.Errorf("%s: To = %q, want %q", id, got, want)
+		}
+	}
+	if got, want := r.Units["0138e2"].CurrentHex, (tndocx.HexCoordinate{Grid: "qq", Column: 7, Row: 9}); got != want {
+		t.Errorf("0138e2: CurrentHex = %v, want %v", got, want)
+	}
+	wantWarnings := []tndocx.Warning{
+		{UnitId: "0138e3", Message: "follows cycle: 0138e3 -> 0138e4 -> 0138e3"},
+	}
+	if !reflect.DeepEqual(r.Warnings, wantWarnings) {
+		t.Errorf("Warnings = %+v, want %+v", r.Warnings, wantWarnings)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"sort"
+)
+
+// Graph is the set of hexes and transitions between them for all the units in a report.
+// It is intended to be exported to external graph or network analysis tools.
+type Graph struct {
+	Nodes []string    `json:"nodes,omitempty"`
+	Edges []GraphEdge `json:"edges,omitempty"`
+}
+
+// GraphEdge is a unit's transition from one hex to another.
+type GraphEdge struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	UnitId string `json:"unit-id"`
+}
+
+// MovementGraph returns the graph of transitions for every unit in the report.
+// Each step in a unit's path (see Unit.Path) is an edge, so a unit that moved
+// through three hexes contributes two edges. Steps that didn't change the hex
+// are skipped. A unit without a path, like one that follows another unit, or
+// whose path can't be worked out, contributes a single edge from the hex it
+// started in to the hex it ended in.
+// Transitions involving unknown hexes are skipped. Obscured hexes ("## 0709") are
+// treated as unknown, since units in different hidden grids would otherwise share a node.
+// Hexes are written in canonical form so that different spellings are the same node.
+// Nodes and edges are sorted so that the output is deterministic; a unit's edges
+// are in the order that it moved.
+func (r *Report) MovementGraph() *Graph {
+	g := &Graph{}
+	nodes := map[string]bool{}
+	addEdge := func(from, to HexCoordinate, unitId string) {
+		if from == to || from.Obscured || to.Obscured {
+			return
+		}
+		g.Edges = append(g.Edges, GraphEdge{From: from.String(), To: to.String(), UnitId: unitId})
+		nodes[from.String()], nodes[to.String()] = true, true
+	}
+	for _, unit := range r.Units {
+		if path := unit.Path(false); len(path) > 1 {
+			for n := 1; n < len(path); n++ {
+				addEdge(path[n-1], path[n], unit.Id)
+			}
+			continue
+		}
+		from, ok := parseHexCoordinate(unit.From)
+		if !ok {
+			continue
+		}
+		if to, ok := parseHexCoordinate(unit.To); ok {
+			addEdge(from, to, unit.Id)
+		}
+	}
+	for node := range nodes {
+		g.Nodes = append(g.Nodes, node)
+	}
+	sort.Strings(g.Nodes)
+	sort.SliceStable(g.Edges, func(i, j int) bool {
+		return g.Edges[i].UnitId < g.Edges[j].UnitId
+	})
+	return g
+}
+
+// isKnownHex returns true if the hex is not empty or "n/a".
+func isKnownHex(hex string) bool {
+	return hex != "" && hex != "n/a"
+}
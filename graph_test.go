@@ -0,0 +1,65 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"github.com/playbymail/tndocx"
+	"reflect"
+	"testing"
+)
+
+func TestReportMovementGraph(t *testing.T) {
+	r := &tndocx.Report{Units: map[string]*tndocx.Unit{
+		"0138":   {Id: "0138", From: "qq 0707", To: "qq 0708"},
+		"0138e1": {Id: "0138e1", From: "QQ0708", To: "qq 0809"},
+		"0138c1": {Id: "0138c1", From: "n/a", To: "qq 0709"},
+		"0138f1": {Id: "0138f1", From: "qq 0709", To: "qq 0709"},
+		"0138f2": {Id: "0138f2", From: "QQ0709", To: "qq 0709"},
+	}}
+	got := r.MovementGraph()
+	want := &tndocx.Graph{
+		Nodes: []string{"QQ 0707", "QQ 0708", "QQ 0809"},
+		Edges: []tndocx.GraphEdge{
+			{From: "QQ 0707", To: "QQ 0708", UnitId: "0138"},
+			{From: "QQ 0708", To: "QQ 0809", UnitId: "0138e1"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MovementGraph() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReportMovementGraphSteps(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0806, (Previous Hex = QQ 0708)",
+		`Tribe Movement: Move N-PR\NE-GH`,
+		"Courier 0138c1, , Current Hex = QQ 0806, (Previous Hex = QQ 0708)",
+		"Tribe Follows 0138",
+	)
+	got := r.MovementGraph()
+	want := &tndocx.Graph{
+		Nodes: []string{"QQ 0707", "QQ 0708", "QQ 0806"},
+		Edges: []tndocx.GraphEdge{
+			{From: "QQ 0708", To: "QQ 0707", UnitId: "0138"},
+			{From: "QQ 0707", To: "QQ 0806", UnitId: "0138"},
+			{From: "QQ 0708", To: "QQ 0806", UnitId: "0138c1"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MovementGraph() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReportMovementGraphObscured(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = ## 0709, (Previous Hex = ## 0710)",
+		`Tribe Movement: Move N-PR`,
+		"Element 0138e1, , Current Hex = ## 0710, (Previous Hex = ## 0709)",
+		`Tribe Movement: Move S-PR`,
+		"Courier 0138c1, , Current Hex = ## 0709, (Previous Hex = QQ 0710)",
+		"Tribe Follows 0138",
+	)
+	if got := r.MovementGraph(); len(got.Nodes) != 0 || len(got.Edges) != 0 {
+		t.Errorf("MovementGraph() = %+v, want no nodes or edges for obscured hexes", got)
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HexCoordinate is a hex on the map, like "qq 0709".
+// The two letters are the grid and the four digits are the column and row within the grid.
+// Reports hide the grid as "##" when it isn't known to the player. Those hexes are
+// Obscured; the column and row are still valid within the hidden grid.
+// The zero value is an unknown hex.
+//
+// A HexCoordinate is encoded as text in its canonical form, like "QQ 0709",
+// "## 0709", or "n/a" for an unknown hex.
+type HexCoordinate struct {
+	Grid     string
+	Column   int
+	Row      int
+	Obscured bool
+}
+
+var (
+	// rxHexCoordinate captures the grid, column, and row from a hex.
+	// the space is optional and the grid may be upper or lower case.
+	// these look like:
+	// - ## 0709
+	// - qq 0709
+	// - QQ0709
+	rxHexCoordinate = regexp.MustCompile(`^(##|[a-zA-Z]{2}) ?(\d{2})(\d{2})$`)
+
+	// rxGridCode matches a two-letter grid code.
+	rxGridCode = regexp.MustCompile(`^[a-z]{2}$`)
+
+	// rxNamedGridHex captures a grid name and the column and row from a unit header.
+	// these look like:
+	// - current hex = north reach 0709
+	// - (previous hex = north reach 0709)
+	rxNamedGridHex = regexp.MustCompile(`hex = ([a-z][a-z ]+?) (\d{4})\b`)
+)
+
+// parseHexCoordinate returns the hex coordinate for a hex from a report.
+// Extra spaces are ignored and the grid is forced to lower case.
+// Returns false if the hex is unknown ("n/a") or not formatted as a hex.
+func parseHexCoordinate(hex string) (HexCoordinate, bool) {
+	match := rxHexCoordinate.FindStringSubmatch(strings.Join(strings.Fields(hex), " "))
+	if match == nil {
+		return HexCoordinate{}, false
+	}
+	column, _ := strconv.Atoi(match[2])
+	row, _ := strconv.Atoi(match[3])
+	if match[1] == "##" {
+		return HexCoordinate{Column: column, Row: row, Obscured: true}, true
+	}
+	return HexCoordinate{Grid: strings.ToLower(match[1]), Column: column, Row: row}, true
+}
+
+// CanonicalHex returns the canonical form of a hex, like "QQ 0709" or "## 0709".
+// It accepts the spellings found in reports and hand-edited files, like "qq 0709",
+// "QQ0709", or "##  0709". Use it to compare hexes or as a map key.
+// Returns false if the hex is unknown ("n/a") or not formatted as a hex.
+func CanonicalHex(hex string) (string, bool) {
+	h, ok := parseHexCoordinate(hex)
+	if !ok {
+		return "", false
+	}
+	return h.String(), true
+}
+
+// canonicalHexOrInput returns the canonical form of the hex, or the hex unchanged
+// if it isn't formatted as a hex.
+func canonicalHexOrInput(hex string) string {
+	if canonical, ok := CanonicalHex(hex); ok {
+		return canonical
+	}
+	return hex
+}
+
+// ParseHex returns the hex coordinate for a hex like "qq 0709" or "## 0709".
+// The grid may be upper or lower case. It uses a Parser with the default configuration.
+func ParseHex(s []byte) (HexCoordinate, error) {
+	p, _ := NewParser()
+	return p.ParseHex(s)
+}
+
+// ParseHex returns the hex coordinate for a hex like "qq 0709" or "## 0709".
+// Returns ErrUnknownHex if the hex is "n/a", ErrInvalidHex if it isn't formatted
+// as a hex, and ErrHexOutOfRange if the column or row is not on the parser's map.
+func (p *Parser) ParseHex(s []byte) (HexCoordinate, error) {
+	s = bytes.ToLower(bytes.TrimSpace(s))
+	if string(s) == "n/a" {
+		return HexCoordinate{}, ErrUnknownHex
+	}
+	h, ok := parseHexCoordinate(string(s))
+	if !ok {
+		return HexCoordinate{}, fmt.Errorf("%q: %w", s, ErrInvalidHex)
+	}
+	if err := p.CheckHex(h); err != nil {
+		return HexCoordinate{}, err
+	}
+	return h, nil
+}
+
+// resolveGridNames replaces descriptive grid names in a unit header with their grid codes.
+// Returns the updated line and true if any name was replaced.
+// The input line is not modified.
+func (p *Parser) resolveGridNames(line []byte) ([]byte, bool) {
+	if len(p.gridNames) == 0 || !bytes.Contains(line, []byte("hex = ")) {
+		return line, false
+	}
+	replaced := false
+	line = rxNamedGridHex.ReplaceAllFunc(line, func(match []byte) []byte {
+		sub := rxNamedGridHex.FindSubmatch(match)
+		code, ok := p.gridNames[string(sub[1])]
+		if !ok {
+			return match
+		}
+		replaced = true
+		return []byte("hex = " + code + " " + string(sub[2]))
+	})
+	return line, replaced
+}
+
+// ColumnRow returns the column and row of the hex as four digits, like "0709".
+func (h HexCoordinate) ColumnRow() string {
+	return fmt.Sprintf("%02d%02d", h.Column, h.Row)
+}
+
+// String returns the canonical form of the hex, like "QQ 0709" or "## 0709":
+// an upper case grid, a single space, and the zero-padded column and row.
+func (h HexCoordinate) String() string {
+	if h.Obscured {
+		return "## " + h.ColumnRow()
+	}
+	return strings.ToUpper(h.Grid) + " " + h.ColumnRow()
+}
+
+// MarshalText implements encoding.TextMarshaler.
+// The hex is written in its canonical form, or as "n/a" if it is unknown.
+func (h HexCoordinate) MarshalText() ([]byte, error) {
+	if h == (HexCoordinate{}) {
+		return []byte("n/a"), nil
+	}
+	return []byte(h.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+// It accepts the same spellings as CanonicalHex, and "n/a" or an empty string for an unknown hex.
+// Returns ErrInvalidHex if the text isn't formatted as a hex.
+func (h *HexCoordinate) UnmarshalText(text []byte) error {
+	if s := strings.ToLower(strings.TrimSpace(string(text))); s == "" || s == "n/a" {
+		*h = HexCoordinate{}
+		return nil
+	}
+	hex, ok := parseHexCoordinate(string(text))
+	if !ok {
+		return fmt.Errorf("%q: %w", text, ErrInvalidHex)
+	}
+	*h = hex
+	return nil
+}
+
+// Reveal returns the hex placed in the grid. Hexes that aren't obscured are returned unchanged.
+func (h HexCoordinate) Reveal(grid string) HexCoordinate {
+	if !h.Obscured {
+		return h
+	}
+	return HexCoordinate{Grid: grid, Column: h.Column, Row: h.Row}
+}
+
+// Neighbor returns the hex next to this one in the given direction.
+// Columns are offset so that odd columns sit half a hex higher than even ones.
+// Returns false if the direction is not valid or the neighbor would be off the
+// top or left edge of the grid. Moving between grids is not supported.
+func (h HexCoordinate) Neighbor(direction string) (HexCoordinate, bool) {
+	column, row := h.Column, h.Row
+	odd := column%2 == 1
+	switch direction {
+	case "n":
+		row--
+	case "s":
+		row++
+	case "ne", "nw":
+		if direction == "ne" {
+			column++
+		} else {
+			column--
+		}
+		if odd {
+			row--
+		}
+	case "se", "sw":
+		if direction == "se" {
+			column++
+		} else {
+			column--
+		}
+		if !odd {
+			row++
+		}
+	default:
+		return HexCoordinate{}, false
+	}
+	if column < 1 || row < 1 {
+		return HexCoordinate{}, false
+	}
+	return HexCoordinate{Grid: h.Grid, Column: column, Row: row, Obscured: h.Obscured}, true
+}
+
+// HexObservation is what a unit reported about the hex it ended the turn in.
+type HexObservation struct {
+	Hex     HexCoordinate `json:"hex"`
+	UnitId  string        `json:"unit-id"`
+	Terrain string        `json:"terrain,omitempty"`
+	Edges   []*Edge       `json:"edges,omitempty"`
+}
+
+// HexObservations returns the observations for the current hex of each unit in the report,
+// followed by the neighboring hexes that the unit saw without moving.
+// Units whose current hex is unknown are skipped.
+// Observations are sorted by unit id so that the output is deterministic.
+func (r *Report) HexObservations() []HexObservation {
+	var observations []HexObservation
+	for _, id := range r.sortedUnitIds() {
+		unit := r.Units[id]
+		hex, ok := parseHexCoordinate(unit.To)
+		if !ok {
+			continue
+		}
+		obs := HexObservation{Hex: hex, UnitId: unit.Id}
+		if unit.Status != nil {
+			obs.Terrain, obs.Edges = unit.Status.Terrain, unit.Status.Edges
+		}
+		observations = append(observations, obs)
+		for _, seen := range unit.Observations {
+			if seen.Distance != 1 {
+				continue
+			} else if neighbor, ok := hex.Neighbor(seen.Direction); ok {
+				observations = append(observations, HexObservation{Hex: neighbor, UnitId: unit.Id, Terrain: seen.Terrain})
+			}
+		}
+	}
+	return observations
+}
+
+// GroupObscured groups the observations of obscured hexes by column and row.
+// All the observations in a group are for the same hex, so once the grid is
+// known, the whole group can be placed on the map with HexCoordinate.Reveal.
+// Observations of hexes that aren't obscured are ignored.
+func GroupObscured(observations []HexObservation) map[string][]HexObservation {
+	groups := map[string][]HexObservation{}
+	for _, obs := range observations {
+		if obs.Hex.Obscured {
+			groups[obs.Hex.ColumnRow()] = append(groups[obs.Hex.ColumnRow()], obs)
+		}
+	}
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].UnitId < group[j].UnitId
+		})
+	}
+	return groups
+}
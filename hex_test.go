@@ -0,0 +1,310 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/playbymail/tndocx"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGroupObscured(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = ## 0709, (Previous Hex = ## 0708)",
+		"0138 Status: PRAIRIE, O NE, 0138",
+		"Courier 0138c1, , Current Hex = ## 0709, (Previous Hex = ## 0709)",
+		"Element 0138e1, , Current Hex = ## 1012, (Previous Hex = ## 0709)",
+		"Garrison 0138g1, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		"Fleet 0138f1, , Current Hex = N/A, (Previous Hex = N/A)",
+	)
+	observations := r.HexObservations()
+	if len(observations) != 4 {
+		t.Fatalf("len(HexObservations()) = %d, want 4", len(observations))
+	}
+	groups := tndocx.GroupObscured(observations)
+	if len(groups) != 2 {
+		t.Fatalf("len(GroupObscured()) = %d, want 2", len(groups))
+	}
+	group := groups["0709"]
+	if len(group) != 2 || group[0].UnitId != "0138" || group[1].UnitId != "0138c1" {
+		t.Fatalf("GroupObscured()[0709] = %+v, want units 0138 and 0138c1", group)
+	}
+	if group[0].Terrain != "prairie" {
+		t.Errorf("GroupObscured()[0709][0].Terrain = %q, want %q", group[0].Terrain, "prairie")
+	}
+	if len(groups["1012"]) != 1 {
+		t.Errorf("len(GroupObscured()[1012]) = %d, want 1", len(groups["1012"]))
+	}
+	for _, obs := range group {
+		if got := obs.Hex.Reveal("qq").String(); got != "QQ 0709" {
+			t.Errorf("%s: Reveal(qq) = %q, want %q", obs.UnitId, got, "QQ 0709")
+		}
+	}
+}
+
+func TestParserMapSize(t *testing.T) {
+	lines := [][]byte{[]byte("tribe 0138,,current hex = qq 3525,(previous hex = qq 0709)")}
+
+	p, err := tndocx.NewParser()
+	if err != nil {
+		t.Fatalf("NewParser() error = %v", err)
+	}
+	if err := p.CheckHex(tndocx.HexCoordinate{Grid: "qq", Column: 35, Row: 25}); !errors.Is(err, tndocx.ErrHexOutOfRange) {
+		t.Errorf("CheckHex(qq 3525) error = %v, want %v", err, tndocx.ErrHexOutOfRange)
+	}
+	if err := p.CheckHex(tndocx.HexCoordinate{Grid: "qq", Column: 0, Row: 9}); !errors.Is(err, tndocx.ErrHexOutOfRange) {
+		t.Errorf("CheckHex(qq 0009) error = %v, want %v", err, tndocx.ErrHexOutOfRange)
+	}
+	if r := p.ToReport("default", lines); len(r.Warnings) != 1 {
+		t.Errorf("default map: Warnings = %+v, want 1 warning", r.Warnings)
+	}
+
+	p, err = tndocx.NewParser(tndocx.WithMapSize(40, 30))
+	if err != nil {
+		t.Fatalf("NewParser(WithMapSize) error = %v", err)
+	}
+	if err := p.CheckHex(tndocx.HexCoordinate{Grid: "qq", Column: 35, Row: 25}); err != nil {
+		t.Errorf("CheckHex(qq 3525) error = %v, want nil", err)
+	}
+	if r := p.ToReport("variant", lines); len(r.Warnings) != 0 {
+		t.Errorf("variant map: Warnings = %+v, want none", r.Warnings)
+	}
+
+	if _, err := tndocx.NewParser(tndocx.WithMapSize(0, 21)); !errors.Is(err, tndocx.ErrInvalidOption) {
+		t.Errorf("WithMapSize(0, 21) error = %v, want %v", err, tndocx.ErrInvalidOption)
+	}
+}
+
+func TestHexNeighbor(t *testing.T) {
+	for _, tt := range []struct {
+		from, direction, want string
+	}{
+		{from: "qq 0709", direction: "n", want: "QQ 0708"},
+		{from: "qq 0709", direction: "ne", want: "QQ 0808"},
+		{from: "qq 0709", direction: "se", want: "QQ 0809"},
+		{from: "qq 0709", direction: "s", want: "QQ 0710"},
+		{from: "qq 0709", direction: "sw", want: "QQ 0609"},
+		{from: "qq 0709", direction: "nw", want: "QQ 0608"},
+		{from: "qq 0809", direction: "ne", want: "QQ 0909"},
+		{from: "qq 0809", direction: "se", want: "QQ 0910"},
+	} {
+		var column, row int
+		fmt.Sscanf(tt.from[3:], "%02d%02d", &column, &row)
+		got, ok := tndocx.HexCoordinate{Grid: "qq", Column: column, Row: row}.Neighbor(tt.direction)
+		if !ok || got.String() != tt.want {
+			t.Errorf("%s.Neighbor(%s) = %q, %v, want %q", tt.from, tt.direction, got, ok, tt.want)
+		}
+	}
+}
+
+func TestObservationLines(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		"Sight GH to NE",
+		"Sighted Grassy Hills to the S",
+		"Saw Frozen Lake to the N",
+		"0138 Status: PRAIRIE, O NE, 0138",
+	)
+	want := []tndocx.Observation{
+		{Direction: "ne", Terrain: "gh", Distance: 1},
+		{Direction: "s", Terrain: "gh", Distance: 1},
+		{Direction: "n", Terrain: "l", Distance: 1},
+	}
+	if got := r.Units["0138"].Observations; !reflect.DeepEqual(got, want) {
+		t.Errorf("Observations = %+v, want %+v", got, want)
+	}
+	var hexes []string
+	for _, obs := range r.HexObservations() {
+		hexes = append(hexes, obs.Hex.String()+" "+obs.Terrain)
+	}
+	if want := []string{"QQ 0709 prairie", "QQ 0808 gh", "QQ 0710 gh", "QQ 0708 l"}; !reflect.DeepEqual(hexes, want) {
+		t.Errorf("HexObservations() = %q, want %q", hexes, want)
+	}
+}
+
+func TestParserGridNames(t *testing.T) {
+	lines := [][]byte{[]byte("tribe 0138,,current hex = north reach 0709,(previous hex = qq 0708)")}
+
+	r := tndocx.ToReport("default", lines)
+	if unit, ok := r.Units["0138"]; ok && unit.To != "" {
+		t.Errorf("default grids: To = %q, want header to be rejected", unit.To)
+	}
+
+	p, err := tndocx.NewParser(tndocx.WithGridNames(map[string]string{"North Reach": "NR"}))
+	if err != nil {
+		t.Fatalf("NewParser() error = %v", err)
+	}
+	unit, ok := p.ToReport("named", lines).Units["0138"]
+	if !ok {
+		t.Fatalf("named grids: ToReport() did not return unit 0138")
+	}
+	if unit.To != "nr 0709" || unit.From != "qq 0708" {
+		t.Errorf("named grids: From, To = %q, %q, want %q, %q", unit.From, unit.To, "qq 0708", "nr 0709")
+	}
+
+	if _, err := tndocx.NewParser(tndocx.WithGridNames(map[string]string{"North Reach": "north"})); !errors.Is(err, tndocx.ErrInvalidOption) {
+		t.Errorf("WithGridNames(north) error = %v, want %v", err, tndocx.ErrInvalidOption)
+	}
+}
+
+func TestParseHex(t *testing.T) {
+	tests := []struct {
+		input string
+		want  tndocx.HexCoordinate
+		err   error
+	}{
+		{input: "QQ 0709", want: tndocx.HexCoordinate{Grid: "qq", Column: 7, Row: 9}},
+		{input: "## 3021", want: tndocx.HexCoordinate{Column: 30, Row: 21, Obscured: true}},
+		{input: "n/a", err: tndocx.ErrUnknownHex},
+		{input: "qq 709", err: tndocx.ErrInvalidHex},
+		{input: "qq 3100", err: tndocx.ErrHexOutOfRange},
+		{input: "qq 0122", err: tndocx.ErrHexOutOfRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := tndocx.ParseHex([]byte(tt.input))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseHex() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalHex(t *testing.T) {
+	for _, tt := range []struct {
+		input, want string
+		ok          bool
+	}{
+		{input: "qq 0709", want: "QQ 0709", ok: true},
+		{input: "QQ 0709", want: "QQ 0709", ok: true},
+		{input: "Qq0709", want: "QQ 0709", ok: true},
+		{input: " ab  0709 ", want: "AB 0709", ok: true},
+		{input: "## 0709", want: "## 0709", ok: true},
+		{input: "##0709", want: "## 0709", ok: true},
+		{input: "n/a"},
+		{input: "qq 709"},
+	} {
+		if got, ok := tndocx.CanonicalHex(tt.input); got != tt.want || ok != tt.ok {
+			t.Errorf("CanonicalHex(%q) = %q, %v, want %q, %v", tt.input, got, ok, tt.want, tt.ok)
+		}
+	}
+	if got := (tndocx.HexCoordinate{Grid: "ab", Column: 7, Row: 9}).String(); got != "AB 0709" {
+		t.Errorf("String() = %q, want %q", got, "AB 0709")
+	}
+}
+
+func TestHexCoordinateText(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		hex  tndocx.HexCoordinate
+		json string
+	}{
+		{name: "known", hex: tndocx.HexCoordinate{Grid: "ab", Column: 7, Row: 9}, json: `{"hex":"AB 0709"}`},
+		{name: "obscured", hex: tndocx.HexCoordinate{Column: 30, Row: 21, Obscured: true}, json: `{"hex":"## 3021"}`},
+		{name: "unknown", hex: tndocx.HexCoordinate{}, json: `{"hex":"n/a"}`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			type wrapper struct {
+				Hex tndocx.HexCoordinate `json:"hex"`
+			}
+			buf, err := json.Marshal(wrapper{Hex: tt.hex})
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			} else if string(buf) != tt.json {
+				t.Errorf("Marshal() = %s, want %s", buf, tt.json)
+			}
+			var got wrapper
+			if err := json.Unmarshal(buf, &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			} else if got.Hex != tt.hex {
+				t.Errorf("Unmarshal() = %+v, want %+v", got.Hex, tt.hex)
+			}
+		})
+	}
+
+	var h tndocx.HexCoordinate
+	if err := h.UnmarshalText([]byte("qq0709")); err != nil || h != (tndocx.HexCoordinate{Grid: "qq", Column: 7, Row: 9}) {
+		t.Errorf("UnmarshalText(qq0709) = %+v, %v, want qq 0709", h, err)
+	}
+	if err := h.UnmarshalText([]byte("qq 709")); !errors.Is(err, tndocx.ErrInvalidHex) {
+		t.Errorf("UnmarshalText(qq 709) error = %v, want %v", err, tndocx.ErrInvalidHex)
+	}
+}
+
+func TestParserCanonicalHexes(t *testing.T) {
+	p, err := tndocx.NewParser(tndocx.WithCanonicalHexes())
+	if err != nil {
+		t.Fatalf("NewParser() error = %v", err)
+	}
+	r := p.ToReport("test", [][]byte{
+		[]byte("tribe 0138,,current hex = qq 0709,(previous hex = n/a)"),
+		[]byte("tribe goes to qq 0707"),
+	})
+	unit := r.Units["0138"]
+	if unit.To != "QQ 0709" || unit.From != "n/a" {
+		t.Errorf("To, From = %q, %q, want %q, %q", unit.To, unit.From, "QQ 0709", "n/a")
+	}
+	if len(unit.Moves) != 1 || unit.Moves[0].GoesTo != "QQ 0707" {
+		t.Errorf("Moves = %+v, want goes to QQ 0707", unit.Moves)
+	}
+}
+
+func TestReportMergeEdges(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0808, (Previous Hex = QQ 0709)",
+		`Tribe Movement: Move NE-PR, River SE, O N`,
+		"Element 0138e1, , Current Hex = QQ 0909, (Previous Hex = QQ 0910)",
+		`Tribe Movement: Move N-PR, River NW`,
+		"Courier 0138c1, , Current Hex = QQ 0909, (Previous Hex = QQ 1009)",
+		`Tribe Movement: Move NW-PR`,
+	)
+	edges, contradictions := r.MergeEdges()
+	want := []tndocx.HexEdge{
+		{Hex: tndocx.HexCoordinate{Grid: "qq", Column: 8, Row: 8}, Direction: "se", Type: "river", UnitIds: []string{"0138", "0138e1"}},
+	}
+	if !reflect.DeepEqual(edges, want) {
+		t.Fatalf("MergeEdges() edges = %+v, want %+v", edges, want)
+	}
+	wantContradictions := []tndocx.EdgeContradiction{{Edge: want[0], MissingFrom: []string{"0138c1"}}}
+	if !reflect.DeepEqual(contradictions, wantContradictions) {
+		t.Errorf("MergeEdges() contradictions = %+v, want %+v", contradictions, wantContradictions)
+	}
+}
+
+func TestParseReportNormalizesHeaders(t *testing.T) {
+	p, err := tndocx.NewParser(tndocx.WithGridNames(map[string]string{"North Reach": "NR"}))
+	if err != nil {
+		t.Fatalf("NewParser() error = %v", err)
+	}
+	sections, err := p.ParseText([]byte(strings.Join([]string{
+		"Tribe 0138, , Current Hex = North Reach 0709, (Previous Hex = QQ 0708)",
+		"Element 0138e1, , Current Hex = ## 709, (Previous Hex = ## 0709)",
+		"",
+	}, "\n")))
+	if err != nil {
+		t.Fatalf("ParseText() error = %v", err)
+	}
+	r, err := p.ParseReport("test", sections)
+	if err != nil {
+		t.Fatalf("ParseReport() error = %v", err)
+	}
+	if unit, ok := r.Units["0138"]; !ok || unit.To != "nr 0709" {
+		t.Errorf("0138: Units = %v, want To %q", r.Units, "nr 0709")
+	}
+	if unit, ok := r.Units["0138e1"]; !ok || unit.To != "## 0709" {
+		t.Errorf("0138e1: Units = %v, want To %q", r.Units, "## 0709")
+	}
+	if len(r.Warnings) != 1 || !strings.HasPrefix(r.Warnings[0].Message, "hex is missing a leading zero") {
+		t.Errorf("Warnings = %+v, want one for the short hex", r.Warnings)
+	}
+	if len(r.Errors) != 0 {
+		t.Errorf("Errors = %+v, want none", r.Errors)
+	}
+}
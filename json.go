@@ -0,0 +1,81 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// SchemaVersion is the version of the JSON written by Encode.
+// It changes when a change to Report would break readers of older dumps.
+const SchemaVersion = 1
+
+// Encode returns the report as JSON, with the current schema version.
+// Map keys are written in sorted order, so encoding the same report twice
+// gives the same bytes.
+func (r *Report) Encode() ([]byte, error) {
+	data := *r
+	data.Meta.SchemaVersion = SchemaVersion
+	return json.Marshal(data)
+}
+
+// DecodeReport returns the report from the JSON written by Encode.
+// Returns ErrUnknownFormat if the report wasn't generated by this package
+// and ErrUnsupportedSchema if it was written with a different schema version.
+func DecodeReport(data []byte) (*Report, error) {
+	report := &Report{}
+	if err := json.Unmarshal(data, report); err != nil {
+		return nil, err
+	}
+	if report.Meta.GeneratedBy != "tn3" {
+		return nil, fmt.Errorf("generated-by %q: %w", report.Meta.GeneratedBy, ErrUnknownFormat)
+	} else if report.Meta.SchemaVersion != SchemaVersion {
+		return nil, fmt.Errorf("schema-version %d: %w", report.Meta.SchemaVersion, ErrUnsupportedSchema)
+	}
+	if report.Units == nil {
+		report.Units = make(map[string]*Unit)
+	}
+	return report, nil
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// ReadReportJSON reads a report from the JSON written by WriteJSON.
+// The Units map is always allocated, even if the report has no units,
+// so that the result can be used the same way as a report from ToReport.
+func ReadReportJSON(r io.Reader) (*Report, error) {
+	report := &Report{}
+	if err := json.NewDecoder(r).Decode(report); err != nil {
+		return nil, err
+	}
+	if report.Units == nil {
+		report.Units = make(map[string]*Unit)
+	}
+	return report, nil
+}
+
+// ConsistencyHash returns a hash of the parsed contents of the report.
+// The file name and metadata (including the timestamp, the authoring tool,
+// and the legend) are not included, so parsing the same input twice, or the
+// same report saved by different tools, gives the same hash.
+// Units are hashed in order of their id.
+func (r *Report) ConsistencyHash() (string, error) {
+	data := *r
+	data.FileName = ""
+	data.Meta = Report{}.Meta
+	buf, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:]), nil
+}
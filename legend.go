@@ -0,0 +1,81 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"bytes"
+	"regexp"
+)
+
+var (
+	// rxLegendHeading matches the heading of a legend block.
+	// these look like:
+	// - legend
+	// - map key:
+	rxLegendHeading = regexp.MustCompile(`^(?:map )?(?:legend|key)(?: to abbreviations)?:?$`)
+
+	// rxLegendLine captures the code and meaning from a line of a legend block.
+	// the code can't contain a comma, which keeps unit headers from matching.
+	// these look like:
+	// - pr = prairie
+	// - lcm = low conifer mountains
+	rxLegendLine = regexp.MustCompile(`^([^=,]{1,20}?) ?= ?([^=]+)$`)
+)
+
+// findLegend returns the index of the first line of the legend block at the end of the input
+// and the codes and meanings from the block. Some players append a legend explaining their
+// abbreviations after the last unit. The block is an optional heading followed by
+// "code = meaning" lines and must run to the end of the input; blank lines are allowed.
+// A unit header can't be a legend line, so the block always comes after the last unit.
+// Returns len(input) and nil if the input doesn't end with a legend.
+func findLegend(input [][]byte) (int, map[string]string) {
+	// the block is found by working back from the end so that only its lines are checked
+	start := len(input)
+	for start > 0 {
+		if line := bytes.TrimSpace(input[start-1]); len(line) != 0 && !rxLegendLine.Match(line) {
+			break
+		}
+		start--
+	}
+	if start > 0 && rxLegendHeading.Match(bytes.TrimSpace(input[start-1])) {
+		start--
+	}
+	legend := map[string]string{}
+	for _, line := range input[start:] {
+		if match := rxLegendLine.FindSubmatch(bytes.TrimSpace(line)); match != nil {
+			legend[string(bytes.TrimSpace(match[1]))] = string(bytes.TrimSpace(match[2]))
+		}
+	}
+	if len(legend) == 0 {
+		return len(input), nil
+	}
+	return start, legend
+}
+
+// legendOffset returns the offset of the first byte of the legend block at the end of the input,
+// using the same rules as findLegend. It works back from the end of the input so that the
+// lines before the block don't have to be split.
+// Returns len(input) if the input doesn't end with a legend.
+func legendOffset(input []byte) int {
+	start, found := len(input), false
+	for end := len(input); ; {
+		nl := bytes.LastIndexByte(input[:end], '\n')
+		line := bytes.TrimSpace(input[nl+1 : end])
+		if len(line) != 0 && !rxLegendLine.Match(line) {
+			// the line isn't part of the block, but it may be the heading
+			if rxLegendHeading.Match(line) {
+				start = nl + 1
+			}
+			break
+		}
+		start, found = nl+1, found || len(line) != 0
+		if nl < 0 {
+			break
+		}
+		end = nl
+	}
+	if !found {
+		return len(input)
+	}
+	return start
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"bytes"
+	"github.com/playbymail/tndocx"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReportLegend(t *testing.T) {
+	input := []string{
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"Tribe Movement: Move N-PR",
+		"0138 Status: PRAIRIE, 0138",
+		"",
+		"Map Key:",
+		"PR = Prairie",
+		"LCM = Low Conifer Mountains",
+		"",
+		"0138 = my tribe",
+		"N-PR = moved north into prairie",
+	}
+	var unknown []string
+	p, err := tndocx.NewParser(tndocx.OnUnknownLine(func(n int, line []byte, unit *tndocx.Unit) {
+		unknown = append(unknown, string(line))
+	}))
+	if err != nil {
+		t.Fatalf("NewParser() error = %v", err)
+	}
+	var lines [][]byte
+	for _, line := range input {
+		lines = append(lines, tndocx.CompressSpaces(bytes.ToLower([]byte(line))))
+	}
+	r := p.ToReport("test", lines)
+	if unknown != nil {
+		t.Errorf("unknown lines = %q, want none", unknown)
+	}
+	want := map[string]string{"pr": "prairie", "lcm": "low conifer mountains", "0138": "my tribe", "n-pr": "moved north into prairie"}
+	if !reflect.DeepEqual(r.Meta.Legend, want) {
+		t.Errorf("Legend = %q, want %q", r.Meta.Legend, want)
+	}
+	unit := r.Units["0138"]
+	if len(r.Units) != 1 || len(unit.Moves) != 1 || unit.Status == nil || unit.Status.Raw != "prairie,0138" {
+		t.Errorf("Units = %+v, want 0138 with one move and its status", r.Units)
+	}
+
+	sections, err := tndocx.ParseText([]byte(strings.Join(input, "\n")))
+	if err != nil {
+		t.Fatalf("ParseText() error = %v", err)
+	}
+	if len(sections) != 1 || string(sections[0].Status) != "0138 status:prairie,0138" {
+		t.Errorf("sections = %+v, want one section with the unit's status", sections)
+	}
+
+	// a line with an equals sign that isn't at the end of the report isn't a legend
+	r = toReport("Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)", "PR = Prairie", "0138 Status: PRAIRIE, 0138")
+	if r.Meta.Legend != nil || r.Units["0138"].Status == nil {
+		t.Errorf("Legend, Status = %q, %+v, want nil and the unit's status", r.Meta.Legend, r.Units["0138"].Status)
+	}
+}
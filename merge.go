@@ -0,0 +1,147 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MergeStrategy is how MergeReports combines a unit that is in more than one report.
+type MergeStrategy int
+
+const (
+	// MergeReplace replaces the unit from an earlier report with the unit from a later one.
+	MergeReplace MergeStrategy = iota
+	// MergeComplete combines the fields of every copy of the unit into one record.
+	// Use it when a unit's report was split across files, for example when the
+	// status line was pasted into one file and the movement into another.
+	//
+	// The precedence rules are:
+	//   - text fields and counts, other than the hexes, come from the first report that has them.
+	//     A later report with a different value is a conflict; the earlier
+	//     value is kept and a warning is added to the merged report.
+	//   - status, winds, and fleet manifest come from the first report that has them.
+	//   - moves, scouts, observations, and cargo come from the report with the most entries.
+	//     Ties are won by the earlier report.
+	//   - the from and to hexes come from the same report as the moves, so that they
+	//     agree with each other. A different value in another report is a conflict.
+	//   - Still is set if any report sets it. NoReport is set only if every report
+	//     sets it, since a unit with data in any file did report.
+	//   - Stationary, CurrentHex, and PreviousHex are worked out again from the merged hexes.
+	MergeComplete
+)
+
+// MergeReports returns a report with the units from all the reports.
+// The reports should be for the same clan and turn; the turn, clan, season,
+// and weather come from the first report that has them. Warnings, errors,
+// events, and weather effects are kept from every report, in order.
+// The reports are not modified.
+func MergeReports(strategy MergeStrategy, reports ...*Report) *Report {
+	merged := &Report{Units: map[string]*Unit{}}
+	var fileNames []string
+	for n, r := range reports {
+		if n == 0 {
+			merged.Meta = r.Meta
+		}
+		if r.FileName != "" {
+			fileNames = append(fileNames, r.FileName)
+		}
+		if merged.ClanId == "" {
+			merged.ClanId = r.ClanId
+		}
+		if merged.TurnId == "" {
+			merged.TurnId, merged.TurnNumber = r.TurnId, r.TurnNumber
+		}
+		if merged.Season == "" {
+			merged.Season = r.Season
+		}
+		if merged.Weather == "" {
+			merged.Weather = r.Weather
+		}
+		merged.Warnings = append(merged.Warnings, r.Warnings...)
+		merged.Errors = append(merged.Errors, r.Errors...)
+		merged.Events = append(merged.Events, r.Events...)
+		merged.WeatherEffects = append(merged.WeatherEffects, r.WeatherEffects...)
+
+		for _, id := range r.sortedUnitIds() {
+			unit := *r.Units[id]
+			prev, ok := merged.Units[id]
+			if !ok || strategy != MergeComplete {
+				merged.Units[id] = &unit
+				continue
+			}
+			merged.mergeUnit(prev, &unit)
+		}
+	}
+	merged.FileName = strings.Join(fileNames, ",")
+	merged.collectSightings()
+	return merged
+}
+
+// mergeUnit adds the fields from a later copy of a unit to the merged unit
+// using the rules for MergeComplete.
+func (r *Report) mergeUnit(unit, later *Unit) {
+	mergeText := func(field string, value *string, other string) {
+		if *value == "" {
+			*value = other
+		} else if other != "" && other != *value {
+			r.Warnings = append(r.Warnings, Warning{UnitId: unit.Id, Message: fmt.Sprintf("merge: %s: kept %q, ignored %q", field, *value, other)})
+		}
+	}
+	mergeText("kind", &unit.Kind, later.Kind)
+	mergeText("name", &unit.Name, later.Name)
+	// the hexes in the header must agree with the moves, so they come from the same copy of the unit
+	if len(later.Moves) > len(unit.Moves) {
+		from, to := unit.From, unit.To
+		unit.From, unit.To, unit.FromInput, unit.ToInput = later.From, later.To, later.FromInput, later.ToInput
+		unit.Moves = later.Moves
+		mergeText("from", &unit.From, from)
+		mergeText("to", &unit.To, to)
+	} else {
+		mergeText("from", &unit.From, later.From)
+		mergeText("to", &unit.To, later.To)
+	}
+	mergeText("orders", &unit.Orders, later.Orders)
+	mergeText("still-reason", &unit.StillReason, later.StillReason)
+	if unit.Input == "" {
+		unit.Input, unit.IdInput = later.Input, later.IdInput
+	}
+	if unit.FromInput == "" {
+		unit.FromInput = later.FromInput
+	}
+	if unit.ToInput == "" {
+		unit.ToInput = later.ToInput
+	}
+	if unit.Population == 0 {
+		unit.Population = later.Population
+	}
+	if unit.Warriors == 0 {
+		unit.Warriors = later.Warriors
+	}
+
+	if unit.Status == nil {
+		unit.Status = later.Status
+	}
+	if unit.Winds == nil {
+		unit.Winds = later.Winds
+	}
+	if unit.Fleet == nil {
+		unit.Fleet = later.Fleet
+	}
+
+	if len(later.Scouts) > len(unit.Scouts) {
+		unit.Scouts = later.Scouts
+	}
+	if len(later.Observations) > len(unit.Observations) {
+		unit.Observations = later.Observations
+	}
+	if len(later.Cargo) > len(unit.Cargo) {
+		unit.Cargo = later.Cargo
+	}
+
+	unit.Still = unit.Still || later.Still
+	unit.NoReport = unit.NoReport && later.NoReport
+	unit.setHexes()
+	unit.Stationary = isKnownHex(unit.From) && unit.From == unit.To
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"github.com/playbymail/tndocx"
+	"testing"
+)
+
+func TestMergeReportsComplete(t *testing.T) {
+	withStatus := toReport(
+		"Tribe 0138, , Current Hex = QQ 0710, (Previous Hex = QQ 0709)",
+		"0138 Status: PRAIRIE, O NE, 0138",
+		"Courier 0138c1, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+	)
+	withMoves := toReport(
+		"Tribe 0138, , Current Hex = QQ 0710, (Previous Hex = QQ 0709)",
+		`Tribe Movement: Move S-PR`,
+		"Courier 0138c1, , Current Hex = QQ 0711, (Previous Hex = QQ 0709)",
+		`Tribe Movement: Move S-PR\S-PR`,
+	)
+
+	replaced := tndocx.MergeReports(tndocx.MergeReplace, withStatus, withMoves)
+	if unit := replaced.Units["0138"]; unit.Status != nil || len(unit.Moves) != 1 {
+		t.Errorf("replace: 0138 Status, Moves = %+v, %d, want nil, 1", unit.Status, len(unit.Moves))
+	}
+
+	merged := tndocx.MergeReports(tndocx.MergeComplete, withStatus, withMoves)
+	unit := merged.Units["0138"]
+	if unit.Status == nil || unit.Status.Terrain != "prairie" {
+		t.Errorf("complete: 0138 Status = %+v, want prairie", unit.Status)
+	}
+	if len(unit.Moves) != 1 || unit.Moves[0].Direction != "s" {
+		t.Errorf("complete: 0138 Moves = %+v, want one step s", unit.Moves)
+	}
+
+	// the hexes come from the report with the moves, and the conflict is reported
+	courier := merged.Units["0138c1"]
+	if courier.To != "qq 0711" || courier.Stationary {
+		t.Errorf("complete: 0138c1 To, Stationary = %q, %v, want %q, false", courier.To, courier.Stationary, "qq 0711")
+	}
+	if len(courier.Moves) != 2 {
+		t.Errorf("complete: len(0138c1 Moves) = %d, want 2", len(courier.Moves))
+	}
+	if len(merged.Warnings) != 1 || merged.Warnings[0].UnitId != "0138c1" {
+		t.Errorf("complete: Warnings = %+v, want one conflict for 0138c1", merged.Warnings)
+	}
+
+	// merging doesn't change the inputs
+	if withStatus.Units["0138"].Moves != nil || withMoves.Units["0138"].Status != nil {
+		t.Errorf("MergeReports() modified its inputs")
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import "time"
+
+// Metrics receives counters and timings from a Parser. It lets a service export
+// them to Prometheus or a similar system without this package depending on a
+// metrics library. The parser calls the methods from every goroutine that is
+// parsing, so implementations must be safe for concurrent use.
+type Metrics interface {
+	// ReportParsed is called once for each report the parser returns,
+	// with the time it took to parse it.
+	ReportParsed(elapsed time.Duration)
+	// LineClassified is called once for each line of a text report with the kind of line.
+	// ParseReport works on sections rather than lines, so it doesn't call it.
+	LineClassified(kind LineKind)
+	// ParseError is called for each error added to a report's Errors and
+	// for each error that stops a report from being parsed.
+	ParseError(err error)
+}
+
+// LineKind is what the parser decided a line of a report was.
+type LineKind string
+
+const (
+	LineBlank         LineKind = "blank"
+	LineEvent         LineKind = "event"
+	LineFleetMovement LineKind = "fleet-movement"
+	LineFollows       LineKind = "follows"
+	LineGoesTo        LineKind = "goes-to"
+	LineLegend        LineKind = "legend"
+	LineMovement      LineKind = "movement"
+	LineNoReport      LineKind = "no-report"
+	LineObservation   LineKind = "observation"
+	LineOrders        LineKind = "orders"
+	LineScout         LineKind = "scout"
+	LineStatus        LineKind = "status"
+	LineStill         LineKind = "still"
+	LineTurnHeader    LineKind = "turn-header"
+	LineUnitHeader    LineKind = "unit-header"
+	LineUnknown       LineKind = "unknown"
+	LineWeather       LineKind = "weather"
+)
+
+// WithMetrics sets the Metrics that the parser reports to.
+// The default is to discard them.
+func WithMetrics(m Metrics) Option {
+	return func(p *Parser) error {
+		if m == nil {
+			return ErrInvalidOption
+		}
+		p.metrics = m
+		return nil
+	}
+}
+
+// noMetrics is the Metrics used when the caller doesn't set one.
+type noMetrics struct{}
+
+func (noMetrics) ReportParsed(time.Duration) {}
+func (noMetrics) LineClassified(LineKind)    {}
+func (noMetrics) ParseError(error)           {}
+
+// reportParsed sends the timing and the errors in the report to the parser's metrics.
+func (p *Parser) reportParsed(report *Report, started time.Time) {
+	for _, err := range report.Errors {
+		p.metrics.ParseError(err)
+	}
+	p.metrics.ReportParsed(time.Since(started))
+}
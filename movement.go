@@ -0,0 +1,249 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	// rxStepDirection captures the direction and terrain of a step.
+	// a hex on a terrain boundary reports both terrains, separated by a slash.
+	// some reports only give the direction, so the terrain is optional.
+	// these look like:
+	// - ne-gh
+	// - sw-pr/gh
+	// - ne
+	rxStepDirection = regexp.MustCompile(`^(ne|se|sw|nw|n|s)(?:-([a-z]+)(?:/([a-z]+))?)?$`)
+
+	// rxDashChain matches a compact step that uses dashes as the only separator.
+	// these look like:
+	// - n-pr-ne-gh
+	// - n-pr-gh-ch
+	rxDashChain = regexp.MustCompile(`^(ne|se|sw|nw|n|s)-[a-z]+(?:-[a-z]+)+$`)
+
+	// rxNoFord captures the direction from a failed attempt to cross a river.
+	// it looks like: "no ford on river to se of hex"
+	rxNoFord = regexp.MustCompile(`^no ford on river to (ne|se|sw|nw|n|s) of hex`)
+
+	// rxStepFailed matches a note that the unit couldn't take the next step.
+	// these look like:
+	// - not enough m.p's to move to n into swamp
+	// - no ford on river to se of hex
+	// - can't move on lake to n of hex
+	rxStepFailed = regexp.MustCompile(`^(?:not enough m\.?p'?s|no ford on river|(?:can't|cannot|can not) move on)`)
+
+	// rxUnitStill captures the reason a unit couldn't move at all.
+	// these look like:
+	// - cannot move,unit is exhausted
+	// - can't move,besieged
+	// - unable to move,unit is out of supply
+	rxUnitStill = regexp.MustCompile(`^(?:cannot|can't|can not|unable to) move,(?:unit is )?(exhausted|besieged|out of supply)`)
+
+	// rxStepVerb captures the verb in front of a step.
+	// these look like:
+	// - enters ne-pr,dowdy holler
+	// - moves n-gh
+	// - exits s-pr
+	rxStepVerb = regexp.MustCompile(`^([a-z]+) ((?:ne|se|sw|nw|n|s)(?:[-,].*)?)$`)
+
+	// stepActions maps the verbs in front of a step to the action.
+	// verbs that aren't listed are treated as moves.
+	stepActions = map[string]StepAction{
+		"enter": ActionEnter, "entered": ActionEnter, "enters": ActionEnter,
+		"exit": ActionExit, "exited": ActionExit, "exits": ActionExit,
+		"leave": ActionExit, "leaves": ActionExit, "left": ActionExit,
+		"move": ActionMove, "moved": ActionMove, "moves": ActionMove,
+	}
+)
+
+// stepVerb returns the action and the rest of the step when the step starts with a verb.
+// A verb that isn't known is returned as a move.
+// Returns false if the step doesn't start with a verb.
+func stepVerb(text string) (StepAction, string, bool) {
+	match := rxStepVerb.FindStringSubmatch(text)
+	if match == nil || directions[match[1]] {
+		return "", "", false
+	} else if action, ok := stepActions[match[1]]; ok {
+		return action, match[2], true
+	}
+	return ActionMove, match[2], true
+}
+
+// unitStillReason returns the reason from a note saying that the unit couldn't move at all.
+// Returns false if the text is not a unit-level still note.
+func unitStillReason(text string) (string, bool) {
+	match := rxUnitStill.FindStringSubmatch(text)
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+// parseMovement parses the steps in a movement line (everything after "move").
+// Steps are separated by backslashes. A failed step ends the unit's movement,
+// so it is always the last step on the line.
+// A step may start with a verb ("enters ne-pr,dowdy holler") that sets its Action.
+// The verb applies to every step in the text and is kept in the text of each step.
+func (p *Parser) parseMovement(line string) (steps []*Step) {
+	for _, text := range strings.Split(line, "\\") {
+		if text = strings.TrimSpace(text); text == "" {
+			continue
+		}
+		action, verb := ActionMove, ""
+		if a, rest, ok := stepVerb(text); ok {
+			action, verb, text = a, strings.TrimSuffix(text, rest), rest
+		}
+		var parsed []*Step
+		if list, ok := directionList(text); ok {
+			for _, direction := range list {
+				parsed = append(parsed, &Step{Step: direction, Direction: direction})
+			}
+		} else {
+			parsed = p.parseDashChain(text)
+		}
+		for _, step := range parsed {
+			step.Step = verb + step.Step
+			if !step.Still {
+				step.Action = action
+			}
+		}
+		steps = append(steps, parsed...)
+	}
+	reconcileEdges(steps)
+	markBacktracks(steps)
+	return steps
+}
+
+// directionList returns the directions from a run of direction-only steps
+// separated by commas, like "ne,se,sw".
+// Returns false if any of the terms is not a direction.
+func directionList(text string) ([]string, bool) {
+	list := strings.Split(text, ",")
+	for n, term := range list {
+		if list[n] = strings.TrimSpace(term); !directions[list[n]] {
+			return nil, false
+		}
+	}
+	return list, true
+}
+
+// parseStep parses a single step from a movement line.
+// The first segment is the direction and terrain ("ne-gh" or "ne-pr/gh").
+// The terrain is left empty when the step only reports a direction ("ne").
+// The remaining segments may include edges ("river se s") and the ids of units in the hex.
+// When the hex has a settlement, its name is the segment right after the terrain,
+// just like on a status line.
+func (p *Parser) parseStep(text string) *Step {
+	step := &Step{Step: text}
+	segments := strings.Split(text, ",")
+	if match := rxStepDirection.FindStringSubmatch(strings.TrimSpace(segments[0])); match != nil {
+		step.Direction, step.Terrain, step.BoundaryTerrain = match[1], match[2], match[3]
+		var other []string
+		step.Edges, other = p.parseEdges(segments[1:])
+		for _, segment := range other {
+			for _, field := range strings.Fields(segment) {
+				if rxScoutUnitId.MatchString(field) {
+					step.Units = append(step.Units, field)
+				}
+			}
+		}
+		if len(segments) > 1 {
+			step.Settlement = p.stepSettlement(strings.Fields(segments[1]))
+		}
+	} else if rxStepFailed.MatchString(text) {
+		// the unit stayed in the hex it was in, so there's no terrain or direction for this step.
+		// the direction of a failed river crossing is kept in the text for reconcileEdges.
+		step.Still, step.StillReason = true, text
+	}
+	return step
+}
+
+// stepSettlement returns the settlement name from the segment after the terrain of a step.
+// Returns an empty string if the segment is an edge, a neighboring terrain, a resource,
+// or the ids of the units in the hex.
+func (p *Parser) stepSettlement(fields []string) string {
+	if len(fields) == 0 || p.isStatusFeature(fields) || rxScoutUnitId.MatchString(fields[0]) {
+		return ""
+	} else if _, ok := p.neighboringTerrain(fields); ok {
+		return ""
+	}
+	text := strings.Join(fields, " ")
+	if p.vocabulary.Resources[text] || rxPopulation.MatchString(text) || rxWarriors.MatchString(text) {
+		return ""
+	}
+	return unquote(text)
+}
+
+// parseDashChain parses a step that may be written as a dash chain.
+// In a chain, each direction is followed by the terrain of the hex entered
+// ("n-pr-ne-gh" is a step n into prairie and then ne into grassy hills).
+// Any other terms are observations of the hex just entered, so "n-pr-gh-ch"
+// is a single step into prairie with "gh" and "ch" observed.
+// Edges following the chain belong to the last step.
+// Steps that aren't dash chains are returned as a single step.
+func (p *Parser) parseDashChain(text string) (steps []*Step) {
+	chain, edges, _ := strings.Cut(text, ",")
+	if !rxDashChain.MatchString(strings.TrimSpace(chain)) {
+		return []*Step{p.parseStep(text)}
+	}
+	terms := strings.Split(strings.TrimSpace(chain), "-")
+	var observations []string
+	for n := 0; n < len(terms); n++ {
+		if directions[terms[n]] && n+1 < len(terms) {
+			if len(steps) != 0 && observations != nil {
+				steps[len(steps)-1].Observations, observations = strings.Join(observations, ","), nil
+			}
+			steps = append(steps, p.parseStep(terms[n]+"-"+terms[n+1]))
+			n++
+			continue
+		}
+		observations = append(observations, terms[n])
+	}
+	last := steps[len(steps)-1]
+	if observations != nil {
+		last.Observations = strings.Join(observations, ",")
+	}
+	if edges != "" {
+		withEdges := p.parseStep(last.Step + "," + edges)
+		last.Step, last.Edges, last.Units, last.Settlement = withEdges.Step, withEdges.Edges, withEdges.Units, withEdges.Settlement
+	}
+	return steps
+}
+
+// reconcileEdges applies notes about failed river crossings to the edges of the preceding step.
+//
+// A line like "s-gh,river se s\no ford on river to se of hex" reports a river on
+// the se and s edges of the hex, then reports that the river on the se edge
+// couldn't be crossed. The river edge is split so that the se edge is marked
+// impassable and the s edge is just a river.
+func reconcileEdges(steps []*Step) {
+	for n := 1; n < len(steps); n++ {
+		match := rxNoFord.FindStringSubmatch(steps[n].Step)
+		if match == nil {
+			continue
+		}
+		prev, direction := steps[n-1], match[1]
+		impassable := &Edge{Type: "river", Directions: []string{direction}, Impassable: true}
+		found := false
+		for i, edge := range prev.Edges {
+			if edge.Type != "river" || edge.Impassable || !slices.Contains(edge.Directions, direction) {
+				continue
+			}
+			found = true
+			edge.Directions = slices.DeleteFunc(edge.Directions, func(d string) bool { return d == direction })
+			if len(edge.Directions) == 0 {
+				prev.Edges[i] = impassable
+			} else {
+				prev.Edges = slices.Insert(prev.Edges, i, impassable)
+			}
+			break
+		}
+		if !found {
+			// the note tells us there is a river even if the step didn't
+			prev.Edges = append(prev.Edges, impassable)
+		}
+	}
+}
@@ -0,0 +1,432 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"bytes"
+	"github.com/playbymail/tndocx"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// toReport runs the lines through the same pre-processing as the docx2text command.
+func toReport(lines ...string) *tndocx.Report {
+	var input [][]byte
+	for _, line := range lines {
+		input = append(input, tndocx.PreProcessMovementLine(tndocx.CompressSpaces(bytes.ToLower([]byte(line)))))
+	}
+	return tndocx.ToReport("test", input)
+}
+
+func TestMovementNoFordReconciliation(t *testing.T) {
+	r := toReport(
+		"Tribe 0987, , Current Hex = ## 0709, (Previous Hex = ## 0709)",
+		`Tribe Movement: Move S-GH,  L NE,  SE,  S\SW-GH,  L SE,  S\SW-GH,  L SE,  S\SW-GH,  L SE,  S\SW-GH,  L SE,  S\SW-PR,  L SE\S-GH,  L NE, River SE S\No Ford on River to SE of HEX`,
+	)
+	moves := r.Units["0987"].Moves
+	if len(moves) != 8 {
+		t.Fatalf("len(Moves) = %d, want 8", len(moves))
+	}
+	step := moves[6]
+	if step.Direction != "s" || step.Terrain != "gh" {
+		t.Errorf("step 7: Direction, Terrain = %q, %q, want %q, %q", step.Direction, step.Terrain, "s", "gh")
+	}
+	want := []*tndocx.Edge{
+		{Type: "lake", Directions: []string{"ne"}},
+		{Type: "river", Directions: []string{"se"}, Impassable: true},
+		{Type: "river", Directions: []string{"s"}},
+	}
+	if !reflect.DeepEqual(step.Edges, want) {
+		t.Errorf("step 7: Edges = %s, want %s", edgesString(step.Edges), edgesString(want))
+	}
+	// the failed crossing didn't move the unit, so it has no direction
+	if step := moves[7]; !step.Still || step.Direction != "" || step.StillReason != "no ford on river to se of hex" {
+		t.Errorf("step 8: Still, Direction, StillReason = %v, %q, %q, want a still step with no direction", step.Still, step.Direction, step.StillReason)
+	}
+}
+
+func TestGoesToSettlement(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		goesTo     string
+		settlement string
+		distance   int
+	}{
+		{name: "hex only", input: "Tribe Goes to QQ 0707", goesTo: "qq 0707"},
+		{name: "named destination", input: "Tribe Goes to QQ 0707, Dowdy Holler", goesTo: "qq 0707", settlement: "dowdy holler"},
+		{name: "quoted destination", input: `Tribe Goes to QQ 0707, "Dowdy Holler"`, goesTo: "qq 0707", settlement: "dowdy holler"},
+		{name: "distance", input: "Tribe Goes to QQ 0707 (3 hexes)", goesTo: "qq 0707", distance: 3},
+		{name: "one hex", input: "Tribe Goes to QQ 0707 (1 hex), Dowdy Holler", goesTo: "qq 0707", settlement: "dowdy holler", distance: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := toReport("Tribe 0138, , Current Hex = QQ 0707, (Previous Hex = QQ 0709)", tt.input)
+			moves := r.Units["0138"].Moves
+			if len(moves) != 1 {
+				t.Fatalf("len(Moves) = %d, want 1", len(moves))
+			}
+			if moves[0].GoesTo != tt.goesTo {
+				t.Errorf("GoesTo = %q, want %q", moves[0].GoesTo, tt.goesTo)
+			}
+			if moves[0].Settlement != tt.settlement {
+				t.Errorf("Settlement = %q, want %q", moves[0].Settlement, tt.settlement)
+			}
+			if moves[0].Distance != tt.distance {
+				t.Errorf("Distance = %d, want %d", moves[0].Distance, tt.distance)
+			}
+		})
+	}
+}
+
+func TestMovementStepSettlement(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0711, (Previous Hex = QQ 0708)",
+		`Tribe Movement: Move S-PR, Dowdy Holler, River SE, 0987\S-GH, River SW\S-PR, 0987e1\S-PR, ‘Big Stone’`,
+	)
+	moves := r.Units["0138"].Moves
+	if len(moves) != 4 {
+		t.Fatalf("len(Moves) = %d, want 4", len(moves))
+	}
+	for n, want := range []string{"dowdy holler", "", "", "big stone"} {
+		if moves[n].Settlement != want {
+			t.Errorf("step %d: Settlement = %q, want %q", n+1, moves[n].Settlement, want)
+		}
+	}
+	if want := []*tndocx.Edge{{Type: "river", Directions: []string{"se"}}}; !reflect.DeepEqual(moves[0].Edges, want) {
+		t.Errorf("step 1: Edges = %s, want %s", edgesString(moves[0].Edges), edgesString(want))
+	}
+	if want := []string{"0987"}; !reflect.DeepEqual(moves[0].Units, want) {
+		t.Errorf("step 1: Units = %q, want %q", moves[0].Units, want)
+	}
+}
+
+func TestFleetMovementPhases(t *testing.T) {
+	input := []byte("Fleet 0138f1, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)\n" +
+		"CALM NE Fleet Movement: Move NE-O,-(N O)\\N-O\n" +
+		"MILD SW Fleet Movement: Move SW-O\n" +
+		"0138f1 Status: OCEAN, 0138f1\n")
+
+	sections, err := tndocx.ParseText(input)
+	if err != nil {
+		t.Fatalf("ParseText() error = %v", err)
+	} else if got := len(sections[0].Moves.Fleet); got != 2 {
+		t.Errorf("len(Moves.Fleet) = %d, want 2", got)
+	}
+
+	r := toReport(strings.Split(string(input), "\n")...)
+	moves := r.Units["0138f1"].Moves
+	want := []struct {
+		direction string
+		strength  string
+	}{
+		{direction: "ne", strength: "calm"},
+		{direction: "n", strength: "calm"},
+		{direction: "sw", strength: "mild"},
+	}
+	if len(moves) != len(want) {
+		t.Fatalf("len(Moves) = %d, want %d", len(moves), len(want))
+	}
+	for i, w := range want {
+		if moves[i].Direction != w.direction {
+			t.Errorf("move %d: Direction = %q, want %q", i+1, moves[i].Direction, w.direction)
+		}
+		if moves[i].Winds == nil || moves[i].Winds.Strength != w.strength {
+			t.Errorf("move %d: Winds = %+v, want strength %q", i+1, moves[i].Winds, w.strength)
+		}
+	}
+}
+
+func TestUnitStillReasons(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		reason string
+	}{
+		{name: "exhausted in movement line", input: "Tribe Movement: Move Cannot Move, Unit is Exhausted", reason: "exhausted"},
+		{name: "besieged on its own line", input: "Can't Move, Besieged", reason: "besieged"},
+		{name: "out of supply", input: "Tribe Movement: Move Unable to Move, Unit is Out of Supply", reason: "out of supply"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := toReport("Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)", tt.input)
+			unit := r.Units["0138"]
+			if !unit.Still {
+				t.Errorf("Still = false, want true")
+			}
+			if unit.StillReason != tt.reason {
+				t.Errorf("StillReason = %q, want %q", unit.StillReason, tt.reason)
+			}
+			if len(unit.Moves) != 0 {
+				t.Errorf("len(Moves) = %d, want 0", len(unit.Moves))
+			}
+		})
+	}
+}
+
+func TestBoundaryTerrain(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		`Tribe Movement: Move S-PR/GH,  L NE\SW-GH`,
+	)
+	moves := r.Units["0138"].Moves
+	if len(moves) != 2 {
+		t.Fatalf("len(Moves) = %d, want 2", len(moves))
+	}
+	if step := moves[0]; step.Direction != "s" || step.Terrain != "pr" || step.BoundaryTerrain != "gh" {
+		t.Errorf("step 1: Direction, Terrain, BoundaryTerrain = %q, %q, %q, want %q, %q, %q", step.Direction, step.Terrain, step.BoundaryTerrain, "s", "pr", "gh")
+	}
+	if len(moves[0].Edges) != 1 || moves[0].Edges[0].Type != "lake" {
+		t.Errorf("step 1: Edges = %s, want lake ne", edgesString(moves[0].Edges))
+	}
+	if step := moves[1]; step.Terrain != "gh" || step.BoundaryTerrain != "" {
+		t.Errorf("step 2: Terrain, BoundaryTerrain = %q, %q, want %q, %q", step.Terrain, step.BoundaryTerrain, "gh", "")
+	}
+}
+
+func TestDashChainMovement(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		`Tribe Movement: Move N-PR-NE-GH, L SE\S-PR-GH-CH\SE-SW`,
+	)
+	type step struct {
+		direction, terrain, observations string
+		edges                            int
+	}
+	want := []step{
+		{direction: "n", terrain: "pr"},
+		{direction: "ne", terrain: "gh", edges: 1},
+		{direction: "s", terrain: "pr", observations: "gh,ch"},
+		{direction: "se", terrain: "sw"},
+	}
+	moves := r.Units["0138"].Moves
+	if len(moves) != len(want) {
+		t.Fatalf("len(Moves) = %d, want %d", len(moves), len(want))
+	}
+	for i, w := range want {
+		got := step{direction: moves[i].Direction, terrain: moves[i].Terrain, observations: moves[i].Observations, edges: len(moves[i].Edges)}
+		if got != w {
+			t.Errorf("move %d: got %+v, want %+v", i+1, got, w)
+		}
+	}
+}
+
+func TestMovementFailedStep(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		`Tribe Movement: Move NE-PR\SE-GH\Not enough M.P's to move to N into SWAMP`,
+	)
+	moves := r.Units["0138"].Moves
+	if len(moves) != 3 {
+		t.Fatalf("len(Moves) = %d, want 3", len(moves))
+	}
+	for i, want := range []struct{ direction, terrain string }{{"ne", "pr"}, {"se", "gh"}} {
+		if moves[i].Still || moves[i].Direction != want.direction || moves[i].Terrain != want.terrain {
+			t.Errorf("move %d: Still, Direction, Terrain = %v, %q, %q, want false, %q, %q", i+1, moves[i].Still, moves[i].Direction, moves[i].Terrain, want.direction, want.terrain)
+		}
+	}
+	last := moves[2]
+	if !last.Still || last.StillReason != "not enough m.p's to move to n into swamp" {
+		t.Errorf("move 3: Still, StillReason = %v, %q, want true, %q", last.Still, last.StillReason, "not enough m.p's to move to n into swamp")
+	}
+	if last.Terrain != "" {
+		t.Errorf("move 3: Terrain = %q, want empty", last.Terrain)
+	}
+}
+
+func TestMovementDirectionOnly(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		`Tribe Movement: Move NE, SE, SW`,
+		`Scout 1:Scout N\NE-PR\SE, 0250`,
+	)
+	unit := r.Units["0138"]
+	if len(unit.Moves) != 3 {
+		t.Fatalf("len(Moves) = %d, want 3", len(unit.Moves))
+	}
+	for i, direction := range []string{"ne", "se", "sw"} {
+		if move := unit.Moves[i]; move.Direction != direction || move.Terrain != "" || move.Still {
+			t.Errorf("move %d: Direction, Terrain, Still = %q, %q, %v, want %q, \"\", false", i+1, move.Direction, move.Terrain, move.Still, direction)
+		}
+	}
+	steps := unit.Scouts[0].Steps
+	if len(steps) != 3 {
+		t.Fatalf("len(Steps) = %d, want 3", len(steps))
+	}
+	for i, want := range []struct{ direction, terrain string }{{"n", ""}, {"ne", "pr"}, {"se", ""}} {
+		if steps[i].Direction != want.direction || steps[i].Terrain != want.terrain {
+			t.Errorf("scout step %d: Direction, Terrain = %q, %q, want %q, %q", i+1, steps[i].Direction, steps[i].Terrain, want.direction, want.terrain)
+		}
+	}
+	if want := []string{"0250"}; !reflect.DeepEqual(steps[2].Units, want) {
+		t.Errorf("scout step 3: Units = %q, want %q", steps[2].Units, want)
+	}
+}
+
+func TestStepKind(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0707, (Previous Hex = QQ 0709)",
+		`Tribe Movement: Move NE-PR\Not enough M.P's to move to N into SWAMP`,
+		"Tribe Goes to QQ 0707",
+		"Courier 0138c1, , Current Hex = QQ 0707, (Previous Hex = QQ 0709)",
+		"Tribe Follows 0138",
+		"Fleet 0138f1, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"CALM NE Fleet Movement: Move NE-O,-(N O)",
+	)
+	for _, tt := range []struct {
+		unitId string
+		want   []tndocx.StepKind
+	}{
+		{unitId: "0138", want: []tndocx.StepKind{tndocx.StepMove, tndocx.StepMove, tndocx.StepGoesTo}},
+		{unitId: "0138c1", want: []tndocx.StepKind{tndocx.StepFollows}},
+		{unitId: "0138f1", want: []tndocx.StepKind{tndocx.StepFleet}},
+	} {
+		var got []tndocx.StepKind
+		for _, step := range r.Units[tt.unitId].Moves {
+			got = append(got, step.Kind)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Kind = %q, want %q", tt.unitId, got, tt.want)
+		}
+	}
+}
+
+func TestMovementStepUnits(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		`Tribe Movement: Move NE-PR, River SW, Ford S, 0987g1\SE-GH, 0250, 0250e1\Not enough M.P's to move to N into SWAMP`,
+	)
+	moves := r.Units["0138"].Moves
+	if len(moves) != 3 {
+		t.Fatalf("len(Moves) = %d, want 3", len(moves))
+	}
+	if step := moves[0]; step.Step != "ne-pr,river sw,ford s,0987g1" || step.Terrain != "pr" || len(step.Edges) != 2 || !reflect.DeepEqual(step.Units, []string{"0987g1"}) {
+		t.Errorf("move 1: Step, Terrain, Edges, Units = %q, %q, %s, %q, want %q, %q, [river:sw ford:s], [0987g1]",
+			step.Step, step.Terrain, edgesString(step.Edges), step.Units, "ne-pr,river sw,ford s,0987g1", "pr")
+	}
+	if want := []string{"0250", "0250e1"}; !reflect.DeepEqual(moves[1].Units, want) {
+		t.Errorf("move 2: Units = %q, want %q", moves[1].Units, want)
+	}
+	if !moves[2].Still || moves[2].Units != nil {
+		t.Errorf("move 3: Still, Units = %v, %q, want true, []", moves[2].Still, moves[2].Units)
+	}
+}
+
+func TestMovementFailedStepVariants(t *testing.T) {
+	for _, tt := range []struct {
+		name, input, reason string
+	}{
+		{name: "after units", input: `Tribe Movement: Move NE-PR, 0987g1\Not enough M.P's to move to N into SWAMP`, reason: "not enough m.p's to move to n into swamp"},
+		{name: "no ford", input: `Tribe Movement: Move NE-PR, River SE\No Ford on River to SE of HEX`, reason: "no ford on river to se of hex"},
+		{name: "lake", input: `Tribe Movement: Move NE-PR\Can't Move on Lake to N of HEX`, reason: "can't move on lake to n of hex"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := toReport("Tribe 0138, , Current Hex = QQ 0808, (Previous Hex = QQ 0709)", tt.input)
+			moves := r.Units["0138"].Moves
+			if len(moves) != 2 {
+				t.Fatalf("len(Moves) = %d, want 2", len(moves))
+			}
+			if moves[0].Still {
+				t.Errorf("move 1: Still = true, want false")
+			}
+			if last := moves[1]; !last.Still || last.StillReason != tt.reason || last.Terrain != "" {
+				t.Errorf("move 2: Still, StillReason, Terrain = %v, %q, %q, want true, %q, \"\"", last.Still, last.StillReason, last.Terrain, tt.reason)
+			}
+			// the unit stayed put, so the failed step isn't on its path
+			if path := r.Units["0138"].Path(false); len(path) != 2 || path[1].ColumnRow() != "0808" {
+				t.Errorf("Path() = %v, want [QQ 0709 QQ 0808]", path)
+			}
+		})
+	}
+}
+
+func TestMovementStepAction(t *testing.T) {
+	for _, tt := range []struct {
+		name, input string
+		action      tndocx.StepAction
+		step        string
+		terrain     string
+		settlement  string
+	}{
+		{name: "no verb", input: `Tribe Movement: Move NE-PR`, action: tndocx.ActionMove, step: "ne-pr", terrain: "pr"},
+		{name: "moves", input: `Tribe Movement: Move Moves NE-GH`, action: tndocx.ActionMove, step: "moves ne-gh", terrain: "gh"},
+		{name: "enters", input: `Tribe Movement: Move Enters NE-PR, Dowdy Holler`, action: tndocx.ActionEnter, step: "enters ne-pr,dowdy holler", terrain: "pr", settlement: "dowdy holler"},
+		{name: "exits", input: `Tribe Movement: Move Exits NE-PR`, action: tndocx.ActionExit, step: "exits ne-pr", terrain: "pr"},
+		{name: "unknown verb", input: `Tribe Movement: Move Wanders NE-SW`, action: tndocx.ActionMove, step: "wanders ne-sw", terrain: "sw"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := toReport("Tribe 0138, , Current Hex = QQ 0808, (Previous Hex = QQ 0709)", tt.input)
+			moves := r.Units["0138"].Moves
+			if len(moves) != 1 {
+				t.Fatalf("len(Moves) = %d, want 1", len(moves))
+			}
+			step := moves[0]
+			if step.Action != tt.action || step.Step != tt.step {
+				t.Errorf("Action, Step = %q, %q, want %q, %q", step.Action, step.Step, tt.action, tt.step)
+			}
+			if step.Direction != "ne" || step.Terrain != tt.terrain || step.Settlement != tt.settlement {
+				t.Errorf("Direction, Terrain, Settlement = %q, %q, %q, want %q, %q, %q", step.Direction, step.Terrain, step.Settlement, "ne", tt.terrain, tt.settlement)
+			}
+		})
+	}
+
+	// the verb applies to each step of a dash chain
+	r := toReport("Tribe 0138, , Current Hex = QQ 0808, (Previous Hex = QQ 0709)", `Tribe Movement: Move Enters N-PR-NE-GH\No Ford on River to SE of HEX`)
+	moves := r.Units["0138"].Moves
+	if len(moves) != 3 {
+		t.Fatalf("len(Moves) = %d, want 3", len(moves))
+	}
+	for i, want := range []tndocx.StepAction{tndocx.ActionEnter, tndocx.ActionEnter, ""} {
+		if moves[i].Action != want {
+			t.Errorf("move %d: Action = %q, want %q", i+1, moves[i].Action, want)
+		}
+	}
+}
+
+func TestFleetMovementObservations(t *testing.T) {
+	for _, tt := range []struct {
+		name, input string
+		want        []tndocx.Observation
+	}{
+		{
+			name:  "direction first",
+			input: `CALM NE Fleet Movement: Move NE-SO,-(NE DO, N O)`,
+			want:  []tndocx.Observation{{Direction: "ne", Terrain: "do", Distance: 1}, {Direction: "n", Terrain: "o", Distance: 1}},
+		},
+		{
+			name:  "terrain first with comma separated directions",
+			input: `CALM NE Fleet Movement: Move NE-SO,-(LCM NE, N, SE O)`,
+			want: []tndocx.Observation{
+				{Direction: "ne", Terrain: "lcm", Distance: 1},
+				{Direction: "n", Terrain: "lcm", Distance: 1},
+				{Direction: "se", Terrain: "o", Distance: 1},
+			},
+		},
+		{
+			name:  "further away and trailing comma",
+			input: `CALM NE Fleet Movement: Move NE-SO,-(N/NE Grassy Hills, N/N O,)`,
+			want:  []tndocx.Observation{{Direction: "n/ne", Terrain: "gh", Distance: 2}, {Direction: "n/n", Terrain: "o", Distance: 2}},
+		},
+		{
+			name:  "nested parentheses",
+			input: `CALM NE Fleet Movement: Move NE-SO,-(NE PR (Arcadia, Port), S O)`,
+			want:  []tndocx.Observation{{Direction: "ne", Terrain: "pr", Distance: 1}, {Direction: "s", Terrain: "o", Distance: 1}},
+		},
+		{
+			name:  "no observations",
+			input: `CALM NE Fleet Movement: Move NE-SO`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := toReport("Fleet 0138f1, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)", tt.input)
+			moves := r.Units["0138f1"].Moves
+			if len(moves) != 1 {
+				t.Fatalf("len(Moves) = %d, want 1", len(moves))
+			}
+			if got := moves[0].FleetObservations; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FleetObservations = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"fmt"
+	"time"
+)
+
+// ParseMultiReport parses a file that holds several turn reports, like a batch upload
+// that is a concatenation of reports from different clans or turns, into one Report per report.
+// It uses a Parser with the default configuration.
+func ParseMultiReport(filename string, input []byte) ([]*Report, error) {
+	p, _ := NewParser()
+	return p.ParseMultiReport(filename, input)
+}
+
+// ParseMultiReport parses a file that holds several turn reports into one Report per report.
+// The input is read the same way as Parse. Each report in the file must start with a turn header
+// or with the unit header that comes before it.
+//
+// Every unit section repeats the turn header, so a new report starts at a turn header only
+// when its turn differs from the current report's. Reports for different clans in the same
+// turn are split where a unit header names a different clan.
+//
+// Units that come before the first turn header of their report are returned in a report
+// with no turn, along with an error wrapping ErrMissingTurnHeader for the first such report.
+// Line numbers in every report count from the start of the file.
+func (p *Parser) ParseMultiReport(filename string, input []byte) ([]*Report, error) {
+	started := time.Now()
+	lines, casing, err := p.readLines(input)
+	if err != nil {
+		p.metrics.ParseError(err)
+		return nil, err
+	}
+	var reports []*Report
+	var orphan error
+	for _, block := range splitReports(lines) {
+		report := p.toReportAt(filename, lines[block.start:block.end], block.start)
+		if casing != nil {
+			casing.restore(report)
+		}
+		setAuthoring(report, input)
+		if report.TurnId == "" && report.TurnNumber == 0 && orphan == nil {
+			orphan = fmt.Errorf("line %d: %w", block.start+1, ErrMissingTurnHeader)
+			p.metrics.ParseError(orphan)
+		}
+		p.reportParsed(report, started)
+		reports = append(reports, report)
+		started = time.Now()
+	}
+	return reports, orphan
+}
+
+// reportBlock is the range of lines for one report in a combined file.
+type reportBlock struct {
+	start, end int
+}
+
+// splitReports returns the ranges of lines for each report in a combined file.
+// A report ends where a unit header names a different clan or where a turn header
+// names a different turn. The turn header follows its unit header in a report,
+// so the new report starts at that unit header if there is one.
+func splitReports(lines [][]byte) []reportBlock {
+	var blocks []reportBlock
+	start, clanId, turnId := 0, "", ""
+	// header is the most recent unit header that hasn't been followed by a turn header
+	header := -1
+	for n, line := range lines {
+		if IsUnitHeader(line) {
+			if id := clanIdFromHeader(line); id != "" {
+				if clanId != "" && id != clanId {
+					blocks = append(blocks, reportBlock{start: start, end: n})
+					start, turnId = n, ""
+				}
+				clanId = id
+			}
+			header = n
+		} else if id, _, ok := parseTurnHeader(line); ok && id != "" {
+			if turnId != "" && id != turnId {
+				at := n
+				if header > start {
+					at = header
+				}
+				blocks = append(blocks, reportBlock{start: start, end: at})
+				start = at
+			}
+			turnId, header = id, -1
+		}
+	}
+	return append(blocks, reportBlock{start: start, end: len(lines)})
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"errors"
+	"github.com/playbymail/tndocx"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseMultiReport(t *testing.T) {
+	input := strings.Join([]string{
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"Current Turn 900-04 (#4), Summer, FINE",
+		"0138 Status: PRAIRIE, 0138",
+		"Element 0138e1, , Current Hex = QQ 0710, (Previous Hex = QQ 0709)",
+		"Current Turn 900-04 (#4), Summer, FINE",
+		"0138e1 Status: PRAIRIE, 0138e1",
+		"Tribe 0250, , Current Hex = RR 0101, (Previous Hex = RR 0101)",
+		"Current Turn 900-04 (#4), Summer, FINE",
+		"0250 Status: PRAIRIE, 0250",
+		"Tribe 0250, , Current Hex = RR 0102, (Previous Hex = RR 0101)",
+		"Current Turn 900-05 (#5), Summer, FINE",
+		"0250 Status: PRAIRIE, 0250",
+	}, "\n")
+	reports, err := tndocx.ParseMultiReport("combined.txt", []byte(input))
+	if err != nil {
+		t.Fatalf("ParseMultiReport() error = %v", err)
+	}
+	type summary struct {
+		ClanId, TurnId string
+		Units          []string
+	}
+	want := []summary{
+		{ClanId: "0138", TurnId: "0900-04", Units: []string{"0138", "0138e1"}},
+		{ClanId: "0250", TurnId: "0900-04", Units: []string{"0250"}},
+		{ClanId: "0250", TurnId: "0900-05", Units: []string{"0250"}},
+	}
+	var got []summary
+	for _, r := range reports {
+		s := summary{ClanId: r.ClanId, TurnId: r.TurnId}
+		for id := range r.Units {
+			s.Units = append(s.Units, id)
+		}
+		sort.Strings(s.Units)
+		got = append(got, s)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseMultiReport() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseMultiReportOrphan(t *testing.T) {
+	input := strings.Join([]string{
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"0138 Status: PRAIRIE, 0138",
+		"Tribe 0250, , Current Hex = RR 0101, (Previous Hex = RR 0101)",
+		"Current Turn 900-04 (#4), Summer, FINE",
+	}, "\n")
+	reports, err := tndocx.ParseMultiReport("combined.txt", []byte(input))
+	if !errors.Is(err, tndocx.ErrMissingTurnHeader) {
+		t.Fatalf("ParseMultiReport() error = %v, want %v", err, tndocx.ErrMissingTurnHeader)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("ParseMultiReport() returned %d reports, want 2", len(reports))
+	}
+	if _, ok := reports[0].Units["0138"]; !ok || reports[0].TurnId != "" {
+		t.Errorf("orphan report: units %v, turn %q", reports[0].Units, reports[0].TurnId)
+	}
+	if reports[1].TurnId != "0900-04" {
+		t.Errorf("second report: turn = %q, want %q", reports[1].TurnId, "0900-04")
+	}
+}
+
+func TestParseMultiReportLineNumbers(t *testing.T) {
+	input := strings.Join([]string{
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"Current Turn 900-04 (#4), Summer, FINE",
+		"0138 Status: PRAIRIE, 0138",
+		"Tribe 0250, , Current Hex = RR 101, (Previous Hex = RR 0101)",
+		"Current Turn 900-04 (#4), Summer, FINE",
+		"Element 0250e1, Current Hex = RR 0101",
+	}, "\n")
+	reports, err := tndocx.ParseMultiReport("combined.txt", []byte(input))
+	if err != nil {
+		t.Fatalf("ParseMultiReport() error = %v", err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("ParseMultiReport() returned %d reports, want 2", len(reports))
+	}
+	second := reports[1]
+	if len(second.Warnings) != 1 || second.Warnings[0].Line != 4 {
+		t.Errorf("Warnings = %+v, want one warning for line 4", second.Warnings)
+	}
+	if len(second.Errors) != 1 || second.Errors[0].Line != 6 {
+		t.Errorf("Errors = %+v, want one error for line 6", second.Errors)
+	}
+	if _, ok := second.Units["unit-006"]; !ok {
+		t.Errorf("Units = %v, want unit-006 for the header on line 6", second.Units)
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"regexp"
+)
+
+// Observation is a terrain that a unit saw without moving into the hex.
+// Distance is the number of hexes away, with 1 being a neighbor of the unit's hex.
+type Observation struct {
+	Direction string `json:"direction"`
+	Terrain   string `json:"terrain"`
+	Distance  int    `json:"distance,omitempty"`
+}
+
+var (
+	// rxObservationLine captures the terrain and direction from an observation-only line.
+	// these look like:
+	// - sight gh to ne
+	// - sighted grassy hills to the se
+	rxObservationLine = regexp.MustCompile(`^(?:sight|sighted|saw) ([a-z]+(?: [a-z]+)*?) to (?:the )?(ne|se|sw|nw|n|s)$`)
+)
+
+// parseObservationLine returns the observation from a line that reports a terrain
+// in a direction without moving there. Returns false if the line isn't an observation.
+// The terrain is normalized through the vocabulary like the terrain on a status line:
+// a seasonal variant is replaced by its base terrain, and names are replaced by their
+// codes, so "sighted grassy hills to the se" and "sight gh to se" are the same observation.
+func (p *Parser) parseObservationLine(line []byte) (Observation, bool) {
+	match := rxObservationLine.FindSubmatch(line)
+	if match == nil {
+		return Observation{}, false
+	}
+	terrain := string(match[1])
+	if seasonal, ok := p.vocabulary.Seasonal[terrain]; ok {
+		terrain = seasonal.Base
+	}
+	return Observation{Direction: string(match[2]), Terrain: p.terrainCodeOrName(terrain), Distance: 1}, true
+}
@@ -5,30 +5,110 @@ package tndocx
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"github.com/playbymail/tndocx/docx"
 	"regexp"
 	"time"
 )
 
+// ParseReport returns a Report containing the units from the sections.
+// It uses a Parser with the default configuration.
 func ParseReport(filename string, sections []*Section) (*Report, error) {
+	p, _ := NewParser()
+	return p.ParseReport(filename, sections)
+}
+
+// ParseReport returns a Report containing the units from the sections.
+// Each section must have a unit header. A header that isn't well-formed still
+// produces a unit, with the header saved in Input and an error added to the report.
+// Sections don't keep line numbers, so warnings and errors from sections have no line number.
+func (p *Parser) ParseReport(filename string, sections []*Section) (*Report, error) {
+	started := time.Now()
 	if len(sections) == 0 {
+		p.metrics.ParseError(ErrEmptyInput)
 		return nil, ErrEmptyInput
 	}
-	report := &Report{
-		FileName: filename,
-		Units:    make(map[string]*Unit),
-	}
-	report.Meta.GeneratedBy = "tn3"
-	report.Meta.Version = version.String()
-	report.Meta.Timestamp = time.Now().UTC().Unix()
+	report := p.newReport(filename)
 	for _, section := range sections {
 		if section.Header == nil {
+			p.metrics.ParseError(ErrMissingElementHeader)
 			return nil, ErrMissingElementHeader
 		}
+		header := p.normalizeHeader(report, 0, section.Header)
+		if report.ClanId == "" {
+			report.ClanId = clanIdFromHeader(header)
+		}
+		unit, ok := unitFromHeader(header)
+		if ok {
+			unit = p.addUnit(report, 0, unit)
+		} else {
+			unit = &Unit{
+				Id:    fmt.Sprintf("unit-%03d", section.Id),
+				Kind:  section.Kind,
+				Input: string(section.Header),
+			}
+			report.Units[unit.Id] = unit
+			report.Errors = append(report.Errors, ReportError{Input: string(section.Header), Err: headerError(header)})
+		}
+		if section.Turn != nil {
+			p.setTurn(report, 0, unit.Id, section.Turn)
+		}
+		if section.NoReport {
+			unit.NoReport = true
+		}
+		if match := rxTribeMovementLine.FindSubmatch(section.Moves.Movement); match != nil {
+			p.addMovement(report, 0, unit, string(match[1]))
+		}
+		if len(section.Moves.Follows) != 0 {
+			// the scrubber removes the "tribe follows" prefix, leaving only the unit id
+			id := bytes.TrimSpace(bytes.TrimPrefix(section.Moves.Follows, []byte("tribe follows")))
+			if rxScoutUnitId.Match(id) {
+				unit.Moves = append(unit.Moves, &Step{Kind: StepFollows, Follows: string(id)})
+			}
+		}
+		if match := rxTribeGoesToLine.FindSubmatch(section.Moves.GoesTo); match != nil {
+			unit.Moves = append(unit.Moves, p.goesToStep(match))
+		}
+		for _, line := range section.Moves.Fleet {
+			if match := rxFleetMovementLine.FindSubmatch(line); match != nil {
+				p.addFleetMovement(unit, match)
+			}
+		}
+		for _, line := range section.Moves.Scouts {
+			if match := rxScoutPatrolLine.FindSubmatch(line); match != nil && p.IsScoutLine(line) {
+				scout := &Scout{Id: string(match[1])}
+				scout.addSteps(string(match[2]))
+				unit.Scouts = append(unit.Scouts, scout)
+			}
+		}
+		if match := rxTribeStatusLine.FindSubmatch(section.Status); match != nil {
+			p.setStatus(report, 0, unit, string(match[1]))
+		}
+		if section.Names != nil {
+			nameCasing(section.Names).restoreUnit(unit)
+		}
 	}
+	report.collectSightings()
+	p.reportParsed(report, started)
 	return report, nil
 }
 
+// clanIdFromHeader returns the clan id for the unit in the header.
+// The clan id is the unit's tribe number with the leading digit forced to zero.
+// Returns an empty string if the header doesn't contain a unit id.
+func clanIdFromHeader(header []byte) string {
+	match := rxHeaderUnitId.FindSubmatch(header)
+	if match == nil {
+		return ""
+	}
+	return "0" + string(match[2][1:4])
+}
+
+var (
+	// rxHeaderUnitId captures the kind and id of the unit from a unit header.
+	rxHeaderUnitId = regexp.MustCompile(`^(courier|element|fleet|garrison|tribe) (\d{4}(?:[cdefg]\d+)?),`)
+)
+
 //func parseUnit(section *Section) (*Unit, error) {
 //	if section.Header != nil {
 //		return nil, ErrMissingElementHeader
@@ -83,8 +163,13 @@ func ParseReport(filename string, sections []*Section) (*Report, error) {
 //	return unit, nil
 //}
 
-// element header should contain four comma separated fields
-func parseElementHeader(elementHeader []byte) *Node {
+// ParseElementHeader returns a tree showing how a unit header was interpreted, which is
+// useful for debugging a header that won't parse. The header should contain four comma
+// separated fields: the unit id, the name, the current hex, and the previous hex.
+// Each field gets a child node with the trimmed text of the field as its Value.
+// A field that can't be parsed has its Error and Input set instead, and any fields
+// after the fourth are kept in an "extra-input" node.
+func ParseElementHeader(elementHeader []byte) *Node {
 	root := &Node{Kind: "element-header"}
 	if len(elementHeader) == 0 {
 		root.Error = ErrMissingElementHeader
@@ -118,7 +203,7 @@ func parseElementHeader(elementHeader []byte) *Node {
 	root.Children = append(root.Children, element)
 
 	name := &Node{Kind: "name"}
-	if len(fields) < 1 {
+	if len(fields) < 2 {
 		name.Error = ErrMissingField
 	} else {
 		field = bytes.TrimSpace(fields[1])
@@ -127,18 +212,20 @@ func parseElementHeader(elementHeader []byte) *Node {
 	root.Children = append(root.Children, name)
 
 	currentHex := &Node{Kind: "current-hex"}
-	if len(fields) < 2 {
+	if len(fields) < 3 {
 		currentHex.Error = ErrMissingField
 	} else {
 		field = bytes.TrimSpace(fields[2])
+		currentHex.Value = string(field)
 	}
 	root.Children = append(root.Children, currentHex)
 
 	previousHex := &Node{Kind: "previous-hex"}
-	if len(fields) < 3 {
+	if len(fields) < 4 {
 		previousHex.Error = ErrMissingField
 	} else {
 		field = bytes.TrimSpace(fields[3])
+		previousHex.Value = string(field)
 	}
 	root.Children = append(root.Children, previousHex)
 
@@ -169,12 +256,17 @@ func isascii(b byte) bool {
 }
 
 func ParseDocx(input []byte) ([]*Section, error) {
-	if docx.DetectWordDocType(input) != docx.Docx {
+	// extract the text from the Word document
+	var text []byte
+	var err error
+	switch docx.DetectWordDocType(input) {
+	case docx.Docx:
+		text, err = docx.ReadBuffer(input)
+	case docx.Doc:
+		text, err = docx.ReadDoc(input)
+	default:
 		return nil, ErrUnknownFormat
 	}
-
-	// extract the text from the Word document
-	text, err := docx.ReadBuffer(input)
 	if err != nil {
 		return nil, err
 	}
@@ -182,17 +274,40 @@ func ParseDocx(input []byte) ([]*Section, error) {
 	return ParseText(text)
 }
 
+// ParseText splits the text of a report into sections.
+// It uses a Parser with the default configuration.
 func ParseText(input []byte) ([]*Section, error) {
+	p, _ := NewParser()
+	return p.ParseText(input)
+}
+
+// ParseText splits the text of a report into sections.
+// Unless scrubbing is disabled, the lines in each section are pre-processed
+// to normalize the punctuation around steps, edges, and unit ids.
+// Scout lines are kept for scouts numbered up to the parser's WithMaxScouts limit.
+func (p *Parser) ParseText(input []byte) ([]*Section, error) {
 	if !(len(input) > 3 && isascii(input[0]) && isascii(input[1]) && isascii(input[2])) {
 		return nil, ErrUnknownFormat
 	}
-	// bug: have to force the entire file to lower case
+	// the expressions only match lower case text, so the names are looked up
+	// in the original text afterward when the parser preserves case.
+	var casing nameCasing
+	if p.preserveCase {
+		casing = nameCasing{}
+		casing.addLines(bytes.Split(CompressSpaces(input), []byte{'\n'})...)
+	}
 	input = bytes.ToLower(input)
 
 	// compress spaces within the input
 	input = CompressSpaces(input)
 
-	sections := SectionInput(input)
+	sections := sectionInput(input, p.maxScouts)
+	for _, section := range sections {
+		section.Names = casing
+	}
+	if p.disableScrub {
+		return sections, nil
+	}
 	//log.Printf("sections %8d bytes into %d sections\n", len(input), len(sections))
 	for _, section := range sections {
 		section.Moves.Movement = scrubMovementLine(section.Moves.Movement)
@@ -201,7 +316,9 @@ func ParseText(input []byte) ([]*Section, error) {
 		//}
 		section.Moves.Follows = scrubFollowsLine(section.Moves.Follows)
 		section.Moves.GoesTo = scrubGoesToLine(section.Moves.GoesTo)
-		section.Moves.Fleet = scrubFleetLine(section.Moves.Fleet)
+		for n, line := range section.Moves.Fleet {
+			section.Moves.Fleet[n] = scrubFleetLine(line)
+		}
 		for n, line := range section.Moves.Scouts {
 			section.Moves.Scouts[n] = scrubScoutLine(line)
 			//log.Printf("section %3d: %s\n", section.Id, section.Moves.Scouts[n])
@@ -358,7 +475,7 @@ func scrubStatusLine(line []byte) []byte {
 //
 //	if step does not start with a comma
 //	   advance step to the next character
-//	else if step starts with an edge code (hsm, lcm, ljm, lsm)
+//	else if step starts with an edge code (hsm, lcm, ljm, lsm) or edge name (river, ford, ...)
 //	     advance step past the edge code
 //	     while step starts with a comma or space followed by a direction code (n, s, ne, se, nw, sw)
 //	           replace the comma with a space
@@ -372,6 +489,9 @@ func scrubStatusLine(line []byte) []byte {
 //	   advance skip to the next character
 //
 // return the line
+//
+// The line is updated in place. The scrubbers always pass in a copy created
+// by their regex replacements, so the caller's input is never modified.
 func scrubStepResults(line []byte) []byte {
 	step := line
 	// advance to the first comma
@@ -416,9 +536,10 @@ func scrubStepResults(line []byte) []byte {
 }
 
 var (
-	// Regular expressions for edge codes, unit IDs, and lists of directions and units
-	edgeCodePattern      = regexp.MustCompile(`^,(hsm|l|lcm|ljm|lsm|o)\b`)
-	unitIDPattern        = regexp.MustCompile(`^,\d{4}([cefg]\d)?\b`)
+	// Regular expressions for edge codes, unit IDs, and lists of directions and units.
+	// Edge codes include the names of the edge features in status lines ("river", "stone road").
+	edgeCodePattern      = regexp.MustCompile(`^,(canal|do|ford|hsm|l|lcm|ljm|lsm|o|pass|river|so|stone road)\b`)
+	unitIDPattern        = regexp.MustCompile(`^,\d{4}([cdefg]\d+)?\b`)
 	listDirectionPattern = regexp.MustCompile(`^[,\s]([ns][ew]?)\b`)
-	listUnitIDPattern    = regexp.MustCompile(`^[,\s]\d{4}([cefg]\d)?\b`)
+	listUnitIDPattern    = regexp.MustCompile(`^[,\s]\d{4}([cdefg]\d+)?\b`)
 )
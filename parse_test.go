@@ -0,0 +1,86 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"errors"
+	"github.com/playbymail/tndocx"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseElementHeader(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		header string
+		want   *tndocx.Node
+	}{
+		{
+			name:   "well-formed",
+			header: "tribe 0138,dowdy holler,current hex = qq 0709,(previous hex = qq 0708)",
+			want: &tndocx.Node{Kind: "element-header", Children: []*tndocx.Node{
+				{Kind: "element-id", Value: "0138"},
+				{Kind: "name", Value: "dowdy holler"},
+				{Kind: "current-hex", Value: "current hex = qq 0709"},
+				{Kind: "previous-hex", Value: "(previous hex = qq 0708)"},
+			}},
+		},
+		{
+			name:   "malformed",
+			header: "element 0138,,current hex = ## 0709,(previous hex = ## 0709),extra,input",
+			want: &tndocx.Node{Kind: "element-header", Children: []*tndocx.Node{
+				{Kind: "element-id", Error: tndocx.ErrInvalidElementId, Input: "element 0138"},
+				{Kind: "name"},
+				{Kind: "current-hex", Value: "current hex = ## 0709"},
+				{Kind: "previous-hex", Value: "(previous hex = ## 0709)"},
+				{Kind: "extra-input", Error: tndocx.ErrUnexpectedInput, Input: "extra,input"},
+			}},
+		},
+	} {
+		if got := tndocx.ParseElementHeader([]byte(tc.header)); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: ParseElementHeader() = %s, want %s", tc.name, nodeString(got), nodeString(tc.want))
+		}
+	}
+}
+
+func TestParseElementHeaderFieldCount(t *testing.T) {
+	fields := []string{"tribe 0138", "dowdy holler", "current hex = qq 0709", "(previous hex = qq 0708)", "extra"}
+	values := []string{"0138", "dowdy holler", "current hex = qq 0709", "(previous hex = qq 0708)"}
+	for n := 1; n <= len(fields); n++ {
+		header := strings.Join(fields[:n], ",")
+		root := tndocx.ParseElementHeader([]byte(header))
+		wantChildren := 4
+		if n > 4 {
+			wantChildren = 5
+		}
+		if len(root.Children) != wantChildren {
+			t.Errorf("%d fields: got %d children, want %d", n, len(root.Children), wantChildren)
+			continue
+		}
+		for i, child := range root.Children[:4] {
+			if i < n {
+				if child.Error != nil || child.Value != values[i] {
+					t.Errorf("%d fields: %s = %s, want value %q", n, child.Kind, nodeString(child), values[i])
+				}
+			} else if !errors.Is(child.Error, tndocx.ErrMissingField) {
+				t.Errorf("%d fields: %s error = %v, want %v", n, child.Kind, child.Error, tndocx.ErrMissingField)
+			}
+		}
+	}
+}
+
+// nodeString returns the node and its children on one line for test messages.
+func nodeString(node *tndocx.Node) string {
+	s := "{" + node.Kind
+	if node.Value != "" {
+		s += " value=" + node.Value
+	}
+	if node.Error != nil {
+		s += " error=" + node.Error.Error() + " input=" + node.Input
+	}
+	for _, child := range node.Children {
+		s += " " + nodeString(child)
+	}
+	return s + "}"
+}
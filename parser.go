@@ -0,0 +1,351 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/playbymail/tndocx/docx"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Parser holds the configuration used when parsing turn reports.
+// Use NewParser to create a Parser with the default configuration.
+type Parser struct {
+	vocabulary    Vocabulary
+	maxScouts     int
+	mapColumns    int
+	mapRows       int
+	gridNames     map[string]string
+	canonicalHex  bool
+	disableScrub  bool
+	sortUnits     bool
+	preserveCase  bool
+	duplicates    DuplicateStrategy
+	onUnknownLine func(lineNumber int, line []byte, currentUnit *Unit)
+	metrics       Metrics
+}
+
+const (
+	// DefaultMaxScouts is the highest scout number accepted by default.
+	// The standard game allows 8 scouts, but some reports number them up to 9.
+	DefaultMaxScouts = 9
+
+	// DefaultMapColumns and DefaultMapRows are the size of a grid on the standard map.
+	DefaultMapColumns = 30
+	DefaultMapRows    = 21
+)
+
+// DuplicateStrategy is what the parser does when a report has more than one header for the same unit.
+type DuplicateStrategy int
+
+const (
+	// DuplicateWarn replaces the earlier unit with the later one and adds a warning. This is the default.
+	// The earlier unit's moves and status are dropped, so the warning names the line of the later header.
+	DuplicateWarn DuplicateStrategy = iota
+	// DuplicateSuffix keeps both units, renaming the later one by adding "-2", "-3", etc. to its id.
+	DuplicateSuffix
+	// DuplicateMerge adds the lines following the later header to the earlier unit.
+	// Moves are concatenated in order and a later status line replaces an earlier one.
+	// The hexes in the later header replace the earlier ones unless they are "n/a".
+	DuplicateMerge
+)
+
+// Option is a function that configures a Parser.
+type Option func(*Parser) error
+
+// NewParser returns a Parser configured for the standard game,
+// with any options applied in order.
+func NewParser(options ...Option) (*Parser, error) {
+	p := &Parser{
+		vocabulary: DefaultVocabulary(),
+		maxScouts:  DefaultMaxScouts,
+		mapColumns: DefaultMapColumns,
+		mapRows:    DefaultMapRows,
+		metrics:    noMetrics{},
+	}
+	for _, option := range options {
+		if err := option(p); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// WithVocabulary replaces the parser's vocabulary.
+func WithVocabulary(v Vocabulary) Option {
+	return func(p *Parser) error {
+		p.vocabulary = v
+		return nil
+	}
+}
+
+// WithMaxScouts sets the highest scout number that the parser accepts.
+// Some variants allow more than the standard 8 scouts.
+func WithMaxScouts(n int) Option {
+	return func(p *Parser) error {
+		if n < 1 {
+			return ErrInvalidOption
+		}
+		p.maxScouts = n
+		return nil
+	}
+}
+
+// WithMapSize sets the number of columns and rows in each grid of the map.
+// Variants may use a different map size than the standard game.
+func WithMapSize(columns, rows int) Option {
+	return func(p *Parser) error {
+		if columns < 1 || rows < 1 {
+			return ErrInvalidOption
+		}
+		p.mapColumns, p.mapRows = columns, rows
+		return nil
+	}
+}
+
+// WithDuplicateStrategy sets what the parser does with duplicate unit headers.
+func WithDuplicateStrategy(strategy DuplicateStrategy) Option {
+	return func(p *Parser) error {
+		if strategy < DuplicateWarn || strategy > DuplicateMerge {
+			return ErrInvalidOption
+		}
+		p.duplicates = strategy
+		return nil
+	}
+}
+
+// WithGridNames lets the parser accept descriptive grid names in place of the
+// two-letter grid code, like "north reach 0709" for "nr 0709".
+// The map is from the grid name to the grid code. Names and codes are forced to
+// lower case to match the parser's input.
+func WithGridNames(names map[string]string) Option {
+	return func(p *Parser) error {
+		p.gridNames = map[string]string{}
+		for name, code := range names {
+			name, code = strings.ToLower(strings.Join(strings.Fields(name), " ")), strings.ToLower(code)
+			if name == "" || !rxGridCode.MatchString(code) {
+				return ErrInvalidOption
+			}
+			p.gridNames[name] = code
+		}
+		return nil
+	}
+}
+
+// WithCanonicalHexes makes the parser write the hexes in unit headers and
+// "goes to" lines in canonical form, like "QQ 0709", instead of the lower case
+// form that the scrubbers produce. See CanonicalHex.
+func WithCanonicalHexes() Option {
+	return func(p *Parser) error {
+		p.canonicalHex = true
+		return nil
+	}
+}
+
+// hex returns the hex in canonical form if the parser is configured to use it.
+// Hexes that aren't formatted as hexes, like "n/a", are returned unchanged.
+func (p *Parser) hex(hex string) string {
+	if !p.canonicalHex {
+		return hex
+	}
+	return canonicalHexOrInput(hex)
+}
+
+// CheckHex returns ErrHexOutOfRange if the hex's column or row is not on the parser's map.
+// Columns and rows start at 1.
+func (p *Parser) CheckHex(h HexCoordinate) error {
+	if h.Column < 1 || h.Column > p.mapColumns || h.Row < 1 || h.Row > p.mapRows {
+		return fmt.Errorf("%s: %w", h, ErrHexOutOfRange)
+	}
+	return nil
+}
+
+// IsScoutLine determines if a line represents a scout command for a scout
+// numbered within the parser's limit.
+func (p *Parser) IsScoutLine(line []byte) bool {
+	return isScoutLine(line, p.maxScouts)
+}
+
+// DisableScrub turns off the pre-processing of the input:
+//   - Parse, ParseConcurrent, and ParseMultiReport don't run PreProcessMovementLine on each line.
+//   - ParseText doesn't run the per-kind scrubbers on the movement, follows, goes to,
+//     fleet, scout, and status lines in each section.
+//
+// Parse and the other report methods never run the per-kind scrubbers.
+// This is useful when the input has already been normalized or when debugging the scrubbers.
+func DisableScrub() Option {
+	return func(p *Parser) error {
+		p.disableScrub = true
+		return nil
+	}
+}
+
+// OnUnknownLine sets a function that ToReport calls for each non-blank line it doesn't recognize.
+// The line number starts at 1. The current unit is the unit from the most recent unit header,
+// or nil if the line comes before the first header. The handler may update the unit.
+// When used with ParseConcurrent, the handler is called from multiple goroutines.
+func OnUnknownLine(fn func(lineNumber int, line []byte, currentUnit *Unit)) Option {
+	return func(p *Parser) error {
+		p.onUnknownLine = fn
+		return nil
+	}
+}
+
+// Parse parses a turn report, which may be a Word document (.docx or legacy .doc) or plain text, into a Report.
+// The text is forced to lower case, spaces are compressed, and, unless scrubbing
+// is disabled, each line is pre-processed before being passed to ToReport.
+// See WithPreserveCase to keep the original spelling of names.
+// The tool that created the input, and the date a Word document was created,
+// are recorded in the report's metadata.
+//
+// The Parser is not modified, so Parse is safe to call from multiple goroutines.
+func (p *Parser) Parse(filename string, input []byte) (*Report, error) {
+	started := time.Now()
+	lines, casing, err := p.readLines(input)
+	if err != nil {
+		p.metrics.ParseError(err)
+		return nil, err
+	}
+	report := p.toReport(filename, lines)
+	if casing != nil {
+		casing.restore(report)
+	}
+	setAuthoring(report, input)
+	p.reportParsed(report, started)
+	return report, nil
+}
+
+// readLines returns the lines of a turn report, which may be a Word document or plain text,
+// in the form that toReport expects. If the parser preserves case, it also returns the
+// original spelling of the names in the report.
+func (p *Parser) readLines(input []byte) ([][]byte, nameCasing, error) {
+	if len(input) == 0 {
+		return nil, nil, ErrEmptyInput
+	}
+	if text, ok, err := readWordText(input, p.preserveCase); err != nil {
+		return nil, nil, err
+	} else if ok {
+		input = text
+	} else {
+		// the scrubbed text is only needed until the spaces are compressed,
+		// so it goes into a pooled scratch buffer instead of a new allocation.
+		scratch := scratchBuffers.Get().(*[]byte)
+		defer scratchBuffers.Put(scratch)
+		*scratch = scrubEOL((*scratch)[:0], input)
+		input = *scratch
+	}
+	var casing nameCasing
+	if p.preserveCase {
+		casing = nameCasing{}
+		casing.addLines(bytes.Split(CompressSpaces(input), []byte{'\n'})...)
+	}
+	input = toLowerASCII(input)
+	lines := bytes.Split(CompressSpaces(input), []byte{'\n'})
+	if !p.disableScrub {
+		for n, line := range lines {
+			lines[n] = PreProcessMovementLine(line)
+		}
+	}
+	return lines, casing, nil
+}
+
+// readWordText returns the text of a Word document (.docx or legacy .doc).
+// The text is in lower case unless originalCase is set.
+// Returns false if the input isn't a Word document.
+func readWordText(input []byte, originalCase bool) ([]byte, bool, error) {
+	var readText func([]byte) ([]byte, error)
+	switch docx.DetectWordDocType(input) {
+	case docx.Docx:
+		readText = docx.ReadBuffer
+		if originalCase {
+			readText = docx.ReadBufferOriginalCase
+		}
+	case docx.Doc:
+		readText = docx.ReadDoc
+		if originalCase {
+			readText = docx.ReadDocOriginalCase
+		}
+	default:
+		return nil, false, nil
+	}
+	text, err := readText(input)
+	if err != nil {
+		return nil, true, err
+	}
+	return text, true, nil
+}
+
+// setAuthoring records the tool that created the input, and the date a Word document
+// was created, in the report's metadata.
+func setAuthoring(report *Report, input []byte) {
+	report.Meta.AuthoringTool = docx.DetectAuthoringTool(input)
+	if created, ok := docx.CreatedDate(input); ok {
+		report.Meta.GeneratedDate = created.Format(time.RFC3339)
+	}
+}
+
+// scratchBuffers holds the buffers that Parse uses for intermediate text.
+// sync.Pool is safe for concurrent use, so ParseConcurrent's workers share it.
+var scratchBuffers = sync.Pool{
+	New: func() any { return new([]byte) },
+}
+
+// toLowerASCII forces the input to lower case in place when it is all ASCII.
+// Otherwise, it returns a lower-cased copy of the input.
+func toLowerASCII(input []byte) []byte {
+	for _, ch := range input {
+		if ch >= utf8.RuneSelf {
+			return bytes.ToLower(input)
+		}
+	}
+	for n, ch := range input {
+		if 'A' <= ch && ch <= 'Z' {
+			input[n] = ch + 'a' - 'A'
+		}
+	}
+	return input
+}
+
+// NamedInput is a turn report and the name of the file it was loaded from.
+type NamedInput struct {
+	Name string
+	Data []byte
+}
+
+// ParseConcurrent parses the inputs using up to workers goroutines.
+// If workers is less than 1, the number of CPUs is used.
+// The reports are returned in the same order as the inputs. If any input fails
+// to parse, its report is nil and the error for the first failed input is returned.
+func (p *Parser) ParseConcurrent(inputs []NamedInput, workers int) ([]*Report, error) {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	reports, errs := make([]*Report, len(inputs)), make([]error, len(inputs))
+	jobs := make(chan int)
+	wg := &sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range jobs {
+				reports[n], errs[n] = p.Parse(inputs[n].Name, inputs[n].Data)
+			}
+		}()
+	}
+	for n := range inputs {
+		jobs <- n
+	}
+	close(jobs)
+	wg.Wait()
+	for n, err := range errs {
+		if err != nil {
+			return reports, fmt.Errorf("%s: %w", inputs[n].Name, err)
+		}
+	}
+	return reports, nil
+}
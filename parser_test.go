@@ -0,0 +1,350 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/playbymail/tndocx"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParserMaxScouts(t *testing.T) {
+	lines := [][]byte{
+		[]byte("tribe 0138,,current hex = ## 0709,(previous hex = ## 0709)"),
+		[]byte("scout 9:scout n-pr"),
+		[]byte("scout 10:scout s-pr"),
+	}
+	tests := []struct {
+		name     string
+		options  []tndocx.Option
+		isScout  bool
+		expected int
+	}{
+		{name: "default", isScout: false, expected: 1},
+		{name: "extended", options: []tndocx.Option{tndocx.WithMaxScouts(12)}, isScout: true, expected: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := tndocx.NewParser(tt.options...)
+			if err != nil {
+				t.Fatalf("NewParser() error = %v", err)
+			}
+			if got := p.IsScoutLine(lines[2]); got != tt.isScout {
+				t.Errorf("IsScoutLine(%q) = %v, want %v", lines[2], got, tt.isScout)
+			}
+			if got := len(p.ToReport("test", lines).Units["0138"].Scouts); got != tt.expected {
+				t.Errorf("len(Scouts) = %d, want %d", got, tt.expected)
+			}
+			sections, err := p.ParseText(bytes.Join(lines, []byte{'\n'}))
+			if err != nil {
+				t.Fatalf("ParseText() error = %v", err)
+			}
+			r, err := p.ParseReport("test", sections)
+			if err != nil {
+				t.Fatalf("ParseReport() error = %v", err)
+			}
+			if got := len(r.Units["0138"].Scouts); got != tt.expected {
+				t.Errorf("ParseText: len(Scouts) = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+
+	if _, err := tndocx.NewParser(tndocx.WithMaxScouts(0)); err != tndocx.ErrInvalidOption {
+		t.Errorf("NewParser() error = %v, want %v", err, tndocx.ErrInvalidOption)
+	}
+}
+
+func TestParserDisableScrub(t *testing.T) {
+	input := []byte("Tribe 0138, , Current Hex = ## 0709, (Previous Hex = ## 0709)\nTribe Movement: Move NE-PR\\\\N-GH\\\n")
+	tests := []struct {
+		name     string
+		options  []tndocx.Option
+		expected string
+	}{
+		{name: "scrubbed", expected: "tribe movement:move ne-pr\\n-gh"},
+		{name: "unscrubbed", options: []tndocx.Option{tndocx.DisableScrub()}, expected: "tribe movement:move ne-pr\\\\n-gh\\"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := tndocx.NewParser(tt.options...)
+			if err != nil {
+				t.Fatalf("NewParser() error = %v", err)
+			}
+			sections, err := p.ParseText(input)
+			if err != nil {
+				t.Fatalf("ParseText() error = %v", err)
+			} else if len(sections) != 1 {
+				t.Fatalf("len(sections) = %d, want 1", len(sections))
+			}
+			if got := string(sections[0].Moves.Movement); got != tt.expected {
+				t.Errorf("Movement = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParserScrubStatusEdges(t *testing.T) {
+	sections, err := tndocx.ParseText([]byte("Tribe 0138, , Current Hex = ## 0709, (Previous Hex = ## 0709)\n0138 Status: PRAIRIE, River N, NE, Stone Road SE, 0138\n"))
+	if err != nil {
+		t.Fatalf("ParseText() error = %v", err)
+	} else if len(sections) != 1 {
+		t.Fatalf("len(sections) = %d, want 1", len(sections))
+	}
+	if got, want := string(sections[0].Status), "0138 status:prairie,river n ne,stone road se,0138"; got != want {
+		t.Errorf("Status = %q, want %q", got, want)
+	}
+	r, err := tndocx.ParseReport("test", sections)
+	if err != nil {
+		t.Fatalf("ParseReport() error = %v", err)
+	}
+	want := []*tndocx.Edge{
+		{Type: "river", Directions: []string{"n", "ne"}},
+		{Type: "stone road", Directions: []string{"se"}},
+	}
+	if got := r.Units["0138"].Status.Edges; !reflect.DeepEqual(got, want) {
+		t.Errorf("Edges = %s, want %s", edgesString(got), edgesString(want))
+	}
+}
+
+func TestParserParseConcurrent(t *testing.T) {
+	p, err := tndocx.NewParser()
+	if err != nil {
+		t.Fatalf("NewParser() error = %v", err)
+	}
+	var inputs []tndocx.NamedInput
+	for i := 0; i < 64; i++ {
+		inputs = append(inputs, tndocx.NamedInput{
+			Name: fmt.Sprintf("input-%02d", i),
+			Data: []byte(fmt.Sprintf("Tribe 0138, , Current Hex = QQ %04d, (Previous Hex = QQ 0709)\r\nCurrent Turn 900-04 (#4), Summer, FINE\r\nTribe Movement: Move NE-PR, O N,  NE\\\\N-GH\r\n0138 Status: PRAIRIE, O NE, 0138\r\n", 101+i)),
+		})
+	}
+	reports, err := p.ParseConcurrent(inputs, 8)
+	if err != nil {
+		t.Fatalf("ParseConcurrent() error = %v", err)
+	}
+	for i, r := range reports {
+		if r.FileName != inputs[i].Name {
+			t.Errorf("report %d: FileName = %q, want %q", i, r.FileName, inputs[i].Name)
+		}
+		unit := r.Units["0138"]
+		if want := fmt.Sprintf("qq %04d", 101+i); unit.To != want {
+			t.Errorf("report %d: To = %q, want %q", i, unit.To, want)
+		}
+		if len(unit.Moves) != 2 || len(unit.Moves[0].Edges) != 1 {
+			t.Errorf("report %d: Moves = %+v, want 2 moves with 1 edge on the first", i, unit.Moves)
+		}
+	}
+
+	inputs = append(inputs, tndocx.NamedInput{Name: "empty"})
+	if _, err := p.ParseConcurrent(inputs, 0); !errors.Is(err, tndocx.ErrEmptyInput) {
+		t.Errorf("ParseConcurrent() error = %v, want %v", err, tndocx.ErrEmptyInput)
+	}
+}
+
+func TestParserOnUnknownLine(t *testing.T) {
+	type call struct {
+		lineNumber int
+		line       string
+		unitId     string
+	}
+	var calls []call
+	p, err := tndocx.NewParser(tndocx.OnUnknownLine(func(lineNumber int, line []byte, currentUnit *tndocx.Unit) {
+		c := call{lineNumber: lineNumber, line: string(line)}
+		if currentUnit != nil {
+			c.unitId = currentUnit.Id
+		}
+		calls = append(calls, c)
+	}))
+	if err != nil {
+		t.Fatalf("NewParser() error = %v", err)
+	}
+	p.ToReport("junk", [][]byte{
+		[]byte("the quick brown fox"),
+		[]byte("tribe 0138,,current hex = qq 0709,(previous hex = qq 0709)"),
+		[]byte(""),
+		[]byte("tribe movement:move ne-pr"),
+		[]byte("jumps over the lazy dog"),
+	})
+	want := []call{
+		{lineNumber: 1, line: "the quick brown fox"},
+		{lineNumber: 5, line: "jumps over the lazy dog", unitId: "0138"},
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("OnUnknownLine calls = %+v, want %+v", calls, want)
+	}
+}
+
+func TestParserDuplicateStrategy(t *testing.T) {
+	input := [][]byte{
+		[]byte("tribe 0138,,current hex = qq 0709,(previous hex = qq 0708)"),
+		[]byte("tribe movement:move n-pr"),
+		[]byte("0138 status:prairie,o ne,0138"),
+		[]byte("tribe 0138,,current hex = qq 0709,(previous hex = qq 0708)"),
+		[]byte("tribe movement:move ne-gh\\se-pr"),
+	}
+	tests := []struct {
+		name     string
+		strategy tndocx.DuplicateStrategy
+		moves    map[string]int
+		status   bool
+	}{
+		{name: "warn", strategy: tndocx.DuplicateWarn, moves: map[string]int{"0138": 2}},
+		{name: "suffix", strategy: tndocx.DuplicateSuffix, moves: map[string]int{"0138": 1, "0138-2": 2}, status: true},
+		{name: "merge", strategy: tndocx.DuplicateMerge, moves: map[string]int{"0138": 3}, status: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := tndocx.NewParser(tndocx.WithDuplicateStrategy(tt.strategy))
+			if err != nil {
+				t.Fatalf("NewParser() error = %v", err)
+			}
+			r := p.ToReport("duplicates", input)
+			if len(r.Units) != len(tt.moves) {
+				t.Errorf("len(Units) = %d, want %d", len(r.Units), len(tt.moves))
+			}
+			for id, want := range tt.moves {
+				unit, ok := r.Units[id]
+				if !ok {
+					t.Errorf("missing unit %s", id)
+					continue
+				}
+				if len(unit.Moves) != want {
+					t.Errorf("%s: len(Moves) = %d, want %d", id, len(unit.Moves), want)
+				}
+			}
+			if got := r.Units["0138"].Status != nil; got != tt.status {
+				t.Errorf("0138: has status = %v, want %v", got, tt.status)
+			}
+			if len(r.Warnings) != 1 || r.Warnings[0].Line != 4 {
+				t.Errorf("Warnings = %+v, want one warning for line 4", r.Warnings)
+			}
+		})
+	}
+
+	if _, err := tndocx.NewParser(tndocx.WithDuplicateStrategy(tndocx.DuplicateStrategy(-1))); !errors.Is(err, tndocx.ErrInvalidOption) {
+		t.Errorf("WithDuplicateStrategy(-1) error = %v, want %v", err, tndocx.ErrInvalidOption)
+	}
+}
+
+// recordingMetrics counts the calls from a parser.
+type recordingMetrics struct {
+	sync.Mutex
+	reports int
+	lines   map[tndocx.LineKind]int
+	errors  []error
+}
+
+func (m *recordingMetrics) ReportParsed(elapsed time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+	m.reports++
+}
+
+func (m *recordingMetrics) LineClassified(kind tndocx.LineKind) {
+	m.Lock()
+	defer m.Unlock()
+	m.lines[kind]++
+}
+
+func (m *recordingMetrics) ParseError(err error) {
+	m.Lock()
+	defer m.Unlock()
+	m.errors = append(m.errors, err)
+}
+
+func TestParserMetrics(t *testing.T) {
+	m := &recordingMetrics{lines: map[tndocx.LineKind]int{}}
+	p, err := tndocx.NewParser(tndocx.WithMetrics(m))
+	if err != nil {
+		t.Fatalf("NewParser() error = %v", err)
+	}
+	input := []byte("Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)\n" +
+		"Current Turn 900-04 (#4), Summer, FINE\n" +
+		"Tribe Movement: Move N-PR\n" +
+		"\n" +
+		"0138 Status: PRAIRIE, 0138\n" +
+		"Element 0138e1, , Current Hex = QQ 0709\n" +
+		"something else\n")
+	if _, err := p.Parse("test", input); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if _, err := p.Parse("empty", nil); err == nil {
+		t.Fatalf("Parse(empty) error = nil, want error")
+	}
+	want := map[tndocx.LineKind]int{
+		tndocx.LineUnitHeader: 2,
+		tndocx.LineTurnHeader: 1,
+		tndocx.LineMovement:   1,
+		tndocx.LineStatus:     1,
+		tndocx.LineBlank:      2, // the empty line and the end of the input
+		tndocx.LineUnknown:    1,
+	}
+	if m.reports != 1 {
+		t.Errorf("ReportParsed() called %d times, want 1", m.reports)
+	}
+	if !reflect.DeepEqual(m.lines, want) {
+		t.Errorf("LineClassified() = %v, want %v", m.lines, want)
+	}
+	if len(m.errors) != 2 || !errors.Is(m.errors[0], tndocx.ErrMissingField) || !errors.Is(m.errors[1], tndocx.ErrEmptyInput) {
+		t.Errorf("ParseError() = %v, want a missing field and empty input", m.errors)
+	}
+
+	if _, err := tndocx.NewParser(tndocx.WithMetrics(nil)); !errors.Is(err, tndocx.ErrInvalidOption) {
+		t.Errorf("WithMetrics(nil) error = %v, want %v", err, tndocx.ErrInvalidOption)
+	}
+}
+
+func BenchmarkParserParse(b *testing.B) {
+	p, err := tndocx.NewParser()
+	if err != nil {
+		b.Fatalf("NewParser() error = %v", err)
+	}
+	var input []byte
+	for i := 0; i < 250; i++ {
+		input = append(input, fmt.Sprintf("Tribe 0138, , Current Hex = QQ %04d, (Previous Hex = QQ 0709)\r\nCurrent Turn 900-04 (#4), Summer, FINE\r\nTribe Movement: Move NE-PR, O N,  NE\\\\N-GH\r\n0138 Status: PRAIRIE, O NE, 0138\r\n", 101+i%20)...)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := p.Parse("bench", input); err != nil {
+			b.Fatalf("Parse() error = %v", err)
+		}
+	}
+}
+
+func TestParserGeneratedDate(t *testing.T) {
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for name, data := range map[string]string{
+		"word/document.xml": `<?xml version="1.0" encoding="UTF-8"?><w:document><w:body><w:p><w:r><w:t>Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)</w:t></w:r></w:p></w:body></w:document>`,
+		"docProps/core.xml": `<?xml version="1.0" encoding="UTF-8"?><cp:coreProperties><dcterms:created xsi:type="dcterms:W3CDTF">2024-02-14T23:30:00Z</dcterms:created></cp:coreProperties>`,
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		} else if _, err = w.Write([]byte(data)); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	p, _ := tndocx.NewParser()
+	r, err := p.Parse("0900-04.0138.report.docx", buf.Bytes())
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if want := "2024-02-14T23:30:00Z"; r.Meta.GeneratedDate != want {
+		t.Errorf("GeneratedDate = %q, want %q", r.Meta.GeneratedDate, want)
+	}
+	if _, ok := r.Units["0138"]; !ok {
+		t.Errorf("Parse() did not return unit 0138")
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+var (
+	// oppositeDirection is the direction that undoes a step in the given direction.
+	oppositeDirection = map[string]string{
+		"n":  "s",
+		"ne": "sw",
+		"se": "nw",
+		"s":  "n",
+		"sw": "ne",
+		"nw": "se",
+	}
+)
+
+// markBacktracks flags steps that reverse the step before them, like ne then sw.
+// Failed steps don't move the unit, so they are skipped when looking for the step before.
+// A "goes to" step moves the unit without a direction, so the step after it is never a backtrack.
+func markBacktracks(steps []*Step) {
+	var prev *Step
+	for _, step := range steps {
+		if step.GoesTo != "" {
+			prev = nil
+			continue
+		} else if step.Still || step.Direction == "" {
+			continue
+		}
+		if prev != nil && oppositeDirection[prev.Direction] == step.Direction {
+			step.Backtrack = true
+		}
+		prev = step
+	}
+}
+
+// Path returns the hexes the unit moved through, starting with the hex it started the turn in.
+// A step that backtracks returns to the hex before the previous one. If collapse is set,
+// the hex it left is dropped instead of being added again, so that maps don't draw the
+// unit going out and back over the same edge.
+// Failed steps are skipped. A "goes to" step adds its destination.
+// Returns nil if the starting hex is unknown or a step would leave the grid.
+func (u *Unit) Path(collapse bool) []HexCoordinate {
+	hex, ok := parseHexCoordinate(u.From)
+	if !ok {
+		return nil
+	}
+	path := []HexCoordinate{hex}
+	for _, step := range u.Moves {
+		if step.GoesTo != "" {
+			if hex, ok = parseHexCoordinate(step.GoesTo); !ok {
+				return nil
+			}
+			path = append(path, hex)
+			continue
+		} else if step.Still || step.Direction == "" {
+			continue
+		}
+		if hex, ok = hex.Neighbor(step.Direction); !ok {
+			return nil
+		}
+		if collapse && step.Backtrack && len(path) > 1 {
+			path = path[:len(path)-1]
+			continue
+		}
+		path = append(path, hex)
+	}
+	return path
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"testing"
+)
+
+func TestUnitPathBacktrack(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0708, (Previous Hex = QQ 0709)",
+		`Tribe Movement: Move NE-PR\SW-GH\N-PR\Not enough M.P's to move to N into SWAMP`,
+	)
+	unit := r.Units["0138"]
+	for i, want := range []bool{false, true, false, false} {
+		if unit.Moves[i].Backtrack != want {
+			t.Errorf("move %d: Backtrack = %v, want %v", i+1, unit.Moves[i].Backtrack, want)
+		}
+	}
+
+	pathString := func(collapse bool) (s string) {
+		for i, hex := range unit.Path(collapse) {
+			if i != 0 {
+				s += " "
+			}
+			s += hex.ColumnRow()
+		}
+		return s
+	}
+	if got, want := pathString(false), "0709 0808 0709 0708"; got != want {
+		t.Errorf("Path(false) = %q, want %q", got, want)
+	}
+	if got, want := pathString(true), "0709 0708"; got != want {
+		t.Errorf("Path(true) = %q, want %q", got, want)
+	}
+}
+
+func TestUnitPathGoesTo(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0705, (Previous Hex = QQ 0709)",
+		`Tribe Movement: Move N-PR`,
+		"Tribe Goes to QQ 0705",
+		`Tribe Movement: Move S-PR`,
+	)
+	unit := r.Units["0138"]
+	if len(unit.Moves) != 3 {
+		t.Fatalf("len(Moves) = %d, want 3", len(unit.Moves))
+	}
+	for i, move := range unit.Moves {
+		if move.Backtrack {
+			t.Errorf("move %d: Backtrack = true, want false", i+1)
+		}
+	}
+	var got string
+	for i, hex := range unit.Path(true) {
+		if i != 0 {
+			got += " "
+		}
+		got += hex.ColumnRow()
+	}
+	if want := "0709 0708 0705 0706"; got != want {
+		t.Errorf("Path(true) = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Render returns the report as the canonical text that ToReport accepts.
+// The text is in the form the parser sees after CompressSpaces and the scrubbers
+// have run: lowercase, with single spaces and no spaces after commas.
+//
+// The turn header and any notes for the whole turn come first, then each unit in
+// order of its id, then the formations and disbands, then the legend. Parsing the
+// rendered text with the same parser options gives the same units, turn, events,
+// and legend. Line numbers in warnings, events, and weather effects are not kept,
+// since they refer to the original input. Units that were kept as raw input because
+// their header couldn't be parsed are rendered as that input.
+func (r *Report) Render() []byte {
+	b := &bytes.Buffer{}
+	if line := r.renderTurnHeader(); line != "" {
+		b.WriteString(line + "\n")
+	}
+	r.renderWeatherEffects(b, "")
+	// a transfer that doesn't name the unit it came from must come before the first unit header
+	for _, event := range r.Events {
+		if event.Kind == EventTransfer && event.UnitId == "" {
+			b.WriteString(renderEvent(event) + "\n")
+		}
+	}
+	for _, id := range r.sortedUnitIds() {
+		r.renderUnit(b, r.Units[id])
+	}
+	for _, event := range r.Events {
+		if event.Kind != EventTransfer || event.UnitId != "" {
+			b.WriteString(renderEvent(event) + "\n")
+		}
+	}
+	if len(r.Meta.Legend) != 0 {
+		b.WriteString("legend\n")
+		for _, code := range sortedKeys(r.Meta.Legend) {
+			b.WriteString(code + " = " + r.Meta.Legend[code] + "\n")
+		}
+	}
+	return b.Bytes()
+}
+
+// renderTurnHeader returns the turn header for the report.
+// Returns an empty string if the report doesn't have a turn.
+func (r *Report) renderTurnHeader() string {
+	if strings.HasPrefix(r.TurnId, "current turn") {
+		// the header couldn't be parsed, so it was kept as it was
+		return r.TurnId
+	} else if r.TurnId == "" && r.TurnNumber == 0 {
+		return ""
+	}
+	line := "current turn " + r.TurnId
+	if r.TurnNumber != 0 {
+		line += fmt.Sprintf("(#%d)", r.TurnNumber)
+	}
+	if r.Season != "" {
+		line += "," + r.Season
+		if r.Weather != "" {
+			line += "," + r.Weather
+		}
+	}
+	return line
+}
+
+// renderWeatherEffects writes the weather effects for the unit.
+// An empty unit id writes the effects for the whole turn.
+func (r *Report) renderWeatherEffects(b *bytes.Buffer, unitId string) {
+	for _, we := range r.WeatherEffects {
+		if we.UnitId == unitId {
+			b.WriteString(we.Condition + "," + we.Effect + "\n")
+		}
+	}
+}
+
+// renderUnit writes the header and the lines for a unit.
+func (r *Report) renderUnit(b *bytes.Buffer, unit *Unit) {
+	if unit.Input != "" {
+		b.WriteString(unit.Input + "\n")
+	} else {
+		b.WriteString(renderUnitHeader(unit) + "\n")
+	}
+	if unit.Orders != "" {
+		b.WriteString(unitPrefix(unit) + "orders:" + unit.Orders + "\n")
+	}
+	for n := 0; n < len(unit.Moves); {
+		step := unit.Moves[n]
+		switch step.Kind {
+		case StepFollows:
+			b.WriteString("tribe follows " + step.Follows + "\n")
+			n++
+		case StepGoesTo:
+			b.WriteString(renderGoesTo(step) + "\n")
+			n++
+		case StepFleet:
+			// the steps for one wind phase are written on one line
+			var steps []string
+			for ; n < len(unit.Moves) && unit.Moves[n].Kind == StepFleet && unit.Moves[n].Winds == step.Winds; n++ {
+				steps = append(steps, renderFleetStep(unit.Moves[n]))
+			}
+			winds := step.Winds
+			if winds == nil {
+				winds = &Winds{}
+			}
+			b.WriteString(fmt.Sprintf("%s %s fleet movement:move %s\n", winds.Strength, winds.Direction, strings.Join(steps, "\\")))
+		default:
+			var steps []string
+			for ; n < len(unit.Moves) && (unit.Moves[n].Kind == StepMove || unit.Moves[n].Kind == ""); n++ {
+				steps = append(steps, renderStep(unit.Moves[n]))
+			}
+			b.WriteString("tribe movement:move " + strings.Join(steps, "\\") + "\n")
+		}
+	}
+	if unit.Still {
+		b.WriteString("cannot move,unit is " + unit.StillReason + "\n")
+	}
+	for _, scout := range unit.Scouts {
+		b.WriteString("scout " + scout.Id + ":scout " + strings.Join(scout.Patrol, "\\") + "\n")
+	}
+	for _, obs := range unit.Observations {
+		b.WriteString("sight " + obs.Terrain + " to " + obs.Direction + "\n")
+	}
+	r.renderWeatherEffects(b, unit.Id)
+	if unit.Status != nil {
+		b.WriteString(unitPrefix(unit) + "status:" + unit.Status.Raw + "\n")
+	}
+	if unit.NoReport {
+		b.WriteString("no report\n")
+	}
+}
+
+// renderUnitHeader returns the header line for a unit.
+// Hexes are written in lowercase since that is what the header expressions match.
+func renderUnitHeader(unit *Unit) string {
+	header := unit.Kind + " " + unit.Id
+	if unit.Name != "" {
+		header += "," + unit.Name
+	}
+	return header + ",current hex = " + strings.ToLower(unit.To) + ",(previous hex = " + strings.ToLower(unit.From) + ")"
+}
+
+// unitPrefix returns the unit id that starts the unit's status and orders lines.
+// Units kept as raw input don't have a valid id, so the prefix is left off.
+func unitPrefix(unit *Unit) string {
+	if unit.Input != "" || !rxScoutUnitId.MatchString(unit.Id) {
+		return ""
+	}
+	return unit.Id + " "
+}
+
+// renderStep returns the text of a step from a movement line.
+// Observations from a dash chain are written back into the chain
+// in front of the edges and units.
+func renderStep(step *Step) string {
+	if step.Observations == "" {
+		return step.Step
+	}
+	chain, rest, ok := strings.Cut(step.Step, ",")
+	chain += "-" + strings.ReplaceAll(step.Observations, ",", "-")
+	if ok {
+		chain += "," + rest
+	}
+	return chain
+}
+
+// renderFleetStep returns the text of a step from a fleet movement line.
+// The observations of the neighboring hexes are already in parentheses.
+func renderFleetStep(step *Step) string {
+	if step.Observations == "" {
+		return step.Step
+	}
+	return step.Step + ",-" + step.Observations
+}
+
+// renderGoesTo returns the line for a "tribe goes to" step.
+func renderGoesTo(step *Step) string {
+	line := "tribe goes to " + strings.ToLower(step.GoesTo)
+	if step.Distance == 1 {
+		line += " (1 hex)"
+	} else if step.Distance != 0 {
+		line += fmt.Sprintf(" (%d hexes)", step.Distance)
+	}
+	if step.Settlement != "" {
+		line += "," + step.Settlement
+	}
+	return line
+}
+
+// renderEvent returns the line for an event.
+func renderEvent(event Event) string {
+	switch event.Kind {
+	case EventFormation:
+		if event.OtherUnitId == "" {
+			return event.UnitId + " formed"
+		}
+		return event.UnitId + " formed from " + event.OtherUnitId
+	case EventDisband:
+		if event.OtherUnitId == "" {
+			return event.UnitId + " disbanded"
+		}
+		return event.UnitId + " disbanded into " + event.OtherUnitId
+	}
+	if event.UnitId == "" {
+		return "transfer " + event.Goods + " to " + event.OtherUnitId
+	}
+	return "transfer " + event.Goods + " from " + event.UnitId + " to " + event.OtherUnitId
+}
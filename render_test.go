@@ -0,0 +1,88 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"bytes"
+	"github.com/playbymail/tndocx"
+	"reflect"
+	"testing"
+)
+
+func TestReportRender(t *testing.T) {
+	r := toReport(
+		"Current Turn 900-04 (#4), Summer, FINE",
+		"Snow, half movement",
+		"Tribe 0138, Dowdy Holler, Current Hex = QQ 0709, (Previous Hex = QQ 0707)",
+		"Orders: Move NE, NE, N",
+		`Tribe Movement: Move N-PR-GH-CH, River SE\NE-GH, 0250\S-GH, River SE S\No Ford on River to SE of HEX`,
+		`Scout 1:Scout NE-GH\N-PR, 0138e1\Can't Move on Lake to N of HEX`,
+		"Sight Grassy Hills to NE",
+		"0138 Status: PRAIRIE, West Harbor, O NE, N, 0138, 0138e1, 2 Horses",
+		"Courier 0138c1, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		"Tribe Follows 0138",
+		"Element 0138e1, , Current Hex = QQ 0705, (Previous Hex = QQ 0709)",
+		"Tribe Goes to QQ 0705 (3 Hexes), Big Stone",
+		"Mud: movement reduced",
+		"0138e1 formed from 0138",
+		"Fleet 0138f1, , Current Hex = QQ 0711, (Previous Hex = QQ 0709)",
+		`CALM NE Fleet Movement: Move NE-SO,-(NE DO, N O)\N-DO`,
+		"MILD N Fleet Movement: Move S-O",
+		"0138f1 Status: OCEAN, O NE, Carrying 0138e1, 0138f1",
+		"Garrison 0138g1, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		"Cannot Move, Unit is Exhausted",
+		"No Report",
+		"transfer 100 grain from 0138 to 0138g1",
+		"Key:",
+		"LCM = Low Conifer Mountains",
+	)
+	if len(r.Errors) != 0 {
+		t.Fatalf("Errors = %+v, want none", r.Errors)
+	}
+
+	out := r.Render()
+	got := tndocx.ToReport("test", bytes.Split(bytes.TrimRight(out, "\n"), []byte("\n")))
+	if len(got.Errors) != 0 {
+		t.Fatalf("rendered report: Errors = %+v, want none\n%s", got.Errors, out)
+	}
+	if got.TurnId != r.TurnId || got.TurnNumber != r.TurnNumber || got.Season != r.Season || got.Weather != r.Weather || got.ClanId != r.ClanId {
+		t.Errorf("turn = %q %d %q %q %q, want %q %d %q %q %q", got.TurnId, got.TurnNumber, got.Season, got.Weather, got.ClanId, r.TurnId, r.TurnNumber, r.Season, r.Weather, r.ClanId)
+	}
+	if !reflect.DeepEqual(got.Units, r.Units) {
+		for id, unit := range r.Units {
+			if !reflect.DeepEqual(got.Units[id], unit) {
+				t.Errorf("%s: rendered unit differs\n%s", id, out)
+			}
+		}
+		if len(got.Units) != len(r.Units) {
+			t.Errorf("len(Units) = %d, want %d", len(got.Units), len(r.Units))
+		}
+	}
+	if !reflect.DeepEqual(got.Meta.Legend, r.Meta.Legend) || len(r.Meta.Legend) != 1 {
+		t.Errorf("Legend = %q, want %q", got.Meta.Legend, r.Meta.Legend)
+	}
+	if !reflect.DeepEqual(got.Sightings, r.Sightings) {
+		t.Errorf("Sightings = %+v, want %+v", got.Sightings, r.Sightings)
+	}
+	// line numbers refer to the input, so they aren't compared
+	for _, events := range [][]tndocx.Event{got.Events, r.Events} {
+		for i := range events {
+			events[i].Line = 0
+		}
+	}
+	if !reflect.DeepEqual(got.Events, r.Events) {
+		t.Errorf("Events = %+v, want %+v", got.Events, r.Events)
+	}
+	for _, effects := range [][]tndocx.WeatherEffect{got.WeatherEffects, r.WeatherEffects} {
+		for i := range effects {
+			effects[i].Line = 0
+		}
+	}
+	if !reflect.DeepEqual(got.WeatherEffects, r.WeatherEffects) {
+		t.Errorf("WeatherEffects = %+v, want %+v", got.WeatherEffects, r.WeatherEffects)
+	}
+
+	if again := got.Render(); !bytes.Equal(again, out) {
+		t.Errorf("Render() is not stable:\n%s\nwant\n%s", again, out)
+	}
+}
@@ -2,33 +2,180 @@
 
 package tndocx
 
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
 // types in an unparsed report file
 
 type Report struct {
-	FileName string           `json:"file-name"`
-	TurnId   string           `json:"turn-id"`
-	Units    map[string]*Unit `json:"units,omitempty"`
-	Meta     struct {
+	FileName   string           `json:"file-name"`
+	ClanId     string           `json:"clan-id,omitempty"`
+	TurnId     string           `json:"turn-id"`
+	TurnNumber int              `json:"turn-number,omitempty"`
+	Season     string           `json:"season,omitempty"`
+	Weather    string           `json:"weather,omitempty"`
+	Units      map[string]*Unit `json:"units,omitempty"`
+	Warnings   []Warning        `json:"warnings,omitempty"`
+
+	// Errors are the lines that couldn't be parsed. It is nil when every line parsed cleanly.
+	Errors []ReportError `json:"errors,omitempty"`
+
+	// Events are the formations, transfers, and disbands in the order they were reported.
+	Events []Event `json:"events,omitempty"`
+
+	// Sightings are the units seen by other units, keyed by the id of the unit that was seen.
+	Sightings map[string][]Sighting `json:"sightings,omitempty"`
+
+	// WeatherEffects are the notes that the weather changed how far units could move.
+	WeatherEffects []WeatherEffect `json:"weather-effects,omitempty"`
+
+	Meta struct {
 		GeneratedBy string `json:"generated-by"`
-		Version     string `json:"version,omitempty"`
-		Timestamp   int64  `json:"timestamp,omitempty"`
+		// SchemaVersion is the version of the JSON schema. See Encode and DecodeReport.
+		SchemaVersion int    `json:"schema-version,omitempty"`
+		Version       string `json:"version,omitempty"`
+		Timestamp     int64  `json:"timestamp,omitempty"`
+		// AuthoringTool is the tool that created the input, when the report was parsed by Parse.
+		AuthoringTool string `json:"authoring-tool,omitempty"`
+		// GeneratedDate is when the report was created, in RFC 3339 format, taken from
+		// the Word document's core properties. It is empty for text reports.
+		GeneratedDate string `json:"generated-date,omitempty"`
+		// Legend is the codes and meanings from a legend block at the end of the report.
+		// The block is kept for reference and isn't parsed as part of any unit.
+		Legend map[string]string `json:"legend,omitempty"`
+		// MapColumns and MapRows are the size of a grid the report was parsed with.
+		// They are zero for reports written before they were added.
+		MapColumns int `json:"map-columns,omitempty"`
+		MapRows    int `json:"map-rows,omitempty"`
 	} `json:"metadata"`
 }
 
+// Warning is a problem found in a report that doesn't stop it from being parsed.
+// Line is the line number in the input, starting at 1, or zero if it doesn't apply.
+type Warning struct {
+	Line    int    `json:"line,omitempty"`
+	UnitId  string `json:"unit-id,omitempty"`
+	Message string `json:"message"`
+}
+
+// ReportError is a line that couldn't be parsed and the error that was hit.
+// Line is the line number in the input, starting at 1, or zero if it doesn't apply.
+type ReportError struct {
+	Line  int    `json:"line,omitempty"`
+	Input string `json:"input"`
+	Err   Error  `json:"error"`
+}
+
+// Error returns the error with its line number, like "line 42: invalid element id".
+func (e ReportError) Error() string {
+	if e.Line == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error so that callers can use errors.Is.
+func (e ReportError) Unwrap() error {
+	return e.Err
+}
+
+// TurnKey returns a stable key for archiving the report, like "0138/0900-04".
+// Missing clan or turn ids are replaced with "unknown" so that the key
+// is always safe to use as a map key or file name.
+func (r *Report) TurnKey() string {
+	clanId, turnId := r.ClanId, r.TurnId
+	if clanId == "" {
+		clanId = "unknown"
+	}
+	if turnId == "" {
+		turnId = "unknown"
+	}
+	return clanId + "/" + turnId
+}
+
+// sortedUnitIds returns the ids of the units in the report in sorted order.
+func (r *Report) sortedUnitIds() []string {
+	ids := make([]string, 0, len(r.Units))
+	for id := range r.Units {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// WalkUnits calls visit for each unit in the report in order of the unit id.
+// The visitor may update the unit. Walking stops at the first error, which is returned.
+func (r *Report) WalkUnits(visit func(*Unit) error) error {
+	for _, id := range r.sortedUnitIds() {
+		if err := visit(r.Units[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type Units []*Unit
 
 type Unit struct {
 	Input     string   `json:"input,omitempty"`
 	Id        string   `json:"id"`
 	IdInput   string   `json:"id-input,omitempty"`
-	From      string   `json:"from,omitempty"`
+	Kind      string   `json:"kind,omitempty"`
+	Name      string   `json:"name,omitempty"`
+	From      string   `json:"from,omitempty"` // the previous hex from the unit header
 	FromInput string   `json:"from-input,omitempty"`
-	To        string   `json:"to,omitempty"`
+	To        string   `json:"to,omitempty"` // the current hex from the unit header
 	ToInput   string   `json:"to-input,omitempty"`
-	Winds     *Winds   `json:"winds,omitempty"`
+	Winds     *Winds   `json:"winds,omitempty"` // winds for the last fleet movement line
 	Moves     []*Step  `json:"moves,omitempty"`
 	Scouts    []*Scout `json:"scouts,omitempty"`
-	Status    string   `json:"status,omitempty"`
+	Status    *Status  `json:"status,omitempty"`
+	Orders    string   `json:"orders,omitempty"`
+
+	// Stationary is set when the previous and current hexes are known and equal,
+	// meaning the unit ended the turn where it started.
+	Stationary bool `json:"stationary,omitempty"`
+
+	// Still and StillReason are set when the unit reports that it couldn't move at all
+	// (for example, "cannot move, unit is exhausted").
+	Still       bool   `json:"still,omitempty"`
+	StillReason string `json:"still-reason,omitempty"`
+
+	// Observations are the terrains the unit saw in neighboring hexes without moving.
+	Observations []Observation `json:"observations,omitempty"`
+
+	// Fleet is the manifest of a fleet, set when its status line reports one.
+	Fleet *Fleet `json:"fleet,omitempty"`
+
+	// NoReport is set when the unit's header is followed by the "no report" placeholder,
+	// meaning that the unit didn't send anything for the turn.
+	NoReport bool `json:"no-report,omitempty"`
+
+	// Population and Warriors are set when the status line reports them.
+	Population int `json:"population,omitempty"`
+	Warriors   int `json:"warriors,omitempty"`
+
+	// Cargo is the goods listed after the units on the status line.
+	Cargo []CargoItem `json:"cargo,omitempty"`
+
+	// CurrentHex is To parsed as a hex: where the unit ended the turn.
+	// PreviousHex is From parsed as a hex: where the unit started the turn.
+	// Either is the zero HexCoordinate when the hex is unknown ("n/a") or not formatted as a hex.
+	CurrentHex  HexCoordinate `json:"current-hex"`
+	PreviousHex HexCoordinate `json:"previous-hex"`
+}
+
+// setHexes parses the unit's From and To into PreviousHex and CurrentHex.
+func (u *Unit) setHexes() {
+	u.PreviousHex, _ = parseHexCoordinate(u.From)
+	u.CurrentHex, _ = parseHexCoordinate(u.To)
 }
 
 type Winds struct {
@@ -37,19 +184,78 @@ type Winds struct {
 }
 
 type Step struct {
-	Follows      string `json:"follows,omitempty"`
-	GoesTo       string `json:"goes-to,omitempty"`
-	Step         string `json:"step,omitempty"`
-	Still        bool   `json:"still,omitempty"`
-	Observations string `json:"observations,omitempty"`
+	Kind            StepKind   `json:"kind,omitempty"`
+	Action          StepAction `json:"action,omitempty"` // set on steps from a tribe movement line
+	Follows         string     `json:"follows,omitempty"`
+	GoesTo          string     `json:"goes-to,omitempty"`
+	Step            string     `json:"step,omitempty"`
+	Still           bool       `json:"still,omitempty"`
+	StillReason     string     `json:"still-reason,omitempty"` // why the step failed, when Still is set
+	Observations    string     `json:"observations,omitempty"`
+	Direction       string     `json:"direction,omitempty"`
+	Terrain         string     `json:"terrain,omitempty"`
+	BoundaryTerrain string     `json:"boundary-terrain,omitempty"` // second terrain for a hex on a boundary ("pr/gh")
+	Edges           []*Edge    `json:"edges,omitempty"`
+	Units           []string   `json:"units,omitempty"` // units found in the hex entered
+	Settlement      string     `json:"settlement,omitempty"`
+	Distance        int        `json:"distance,omitempty"`  // hexes to the goes-to destination, when the line says
+	Winds           *Winds     `json:"winds,omitempty"`     // set on fleet movement steps
+	Backtrack       bool       `json:"backtrack,omitempty"` // set when the step reverses the step before it
+
+	// FleetObservations are the terrains a fleet saw around the hex it entered,
+	// parsed from the parenthesized list in Observations.
+	FleetObservations []Observation `json:"fleet-observations,omitempty"`
 }
 
 type Scout struct {
-	Id     string   `json:"id"`
-	Patrol []string `json:"scout,omitempty"`
-	Still  bool     `json:"still,omitempty"`
+	Id     string       `json:"id"`
+	Patrol []string     `json:"scout,omitempty"`
+	Steps  []*ScoutStep `json:"steps,omitempty"`
+	Still  bool         `json:"still,omitempty"`
 }
 
+// ScoutStep is a single step in a scout patrol.
+// The Outcome tells the map whether the scout reached the hex,
+// was blocked from entering it, or found nothing of interest.
+type ScoutStep struct {
+	Step      string       `json:"step"`
+	Outcome   ScoutOutcome `json:"outcome"`
+	Direction string       `json:"direction,omitempty"`
+	Terrain   string       `json:"terrain,omitempty"`
+	Reason    string       `json:"reason,omitempty"`
+	Units     []string     `json:"units,omitempty"`
+}
+
+// StepKind is how a unit took a step.
+type StepKind string
+
+const (
+	StepMove    StepKind = "move"    // a step from a tribe movement line
+	StepGoesTo  StepKind = "goes-to" // a "tribe goes to" teleport
+	StepFollows StepKind = "follows" // a "tribe follows" line
+	StepFleet   StepKind = "fleet"   // a step from a fleet movement line
+)
+
+// StepAction is what a unit did when it took a step from a tribe movement line.
+// The action comes from the verb in front of the step; a step without a verb is a move.
+type StepAction string
+
+const (
+	ActionMove  StepAction = "move"  // moved into open terrain
+	ActionEnter StepAction = "enter" // entered a settlement or structure
+	ActionExit  StepAction = "exit"  // left a settlement or structure
+)
+
+type ScoutOutcome string
+
+const (
+	ScoutMoved   ScoutOutcome = "moved"
+	ScoutBlocked ScoutOutcome = "blocked"
+	ScoutEmpty   ScoutOutcome = "empty"
+)
+
+// Node is a part of a parsed line, like a field of a unit header, and its parts.
+// See ParseElementHeader.
 type Node struct {
 	Kind     string // always set
 	Value    string // set on successful parse
@@ -58,143 +264,544 @@ type Node struct {
 	Children []*Node
 }
 
-//var (
-//	// rxFleetMovementLine captures fleet movement lines.
-//	rxFleetMovementLine = regexp.MustCompile(`^(calm|mild|strong|gale) (ne|se|sw|nw|n|s) fleet movement:move(.*)$`)
-//	rxFleetObservation  = regexp.MustCompile(`^\([^)]*\)`)
-//
-//	// rxScoutPatrolLine captures scout patrol lines.
-//	rxScoutPatrolLine = regexp.MustCompile(`^scout ([1-8]):scout(.*)$`)
-//
-//	// rxTurnHeaderLine is the regular expression that matches the turn header line.
-//	// that line looks like: "tribe 0138,current hex = ## 0709,(previous hex = ## 0709)"
-//	rxTribeHeaderLine     = regexp.MustCompile(`^(?:courier|element|garrison|fleet|tribe) (\d{4}(?:[cdefg]\d)?),current hex = (n/a|(?:##|[a-z]{2}) \d{4}),\(previous hex = (n/a|(?:##|[a-z]{2}) \d{4})\)$`)
-//	rxTribeHeaderMiscLine = regexp.MustCompile(`^(?:courier|element|garrison|fleet|tribe) (\d{4}(?:[cdefg]\d)?),([^,]*),current hex = (n/a|(?:##|[a-z]{2}) \d{4}),\(previous hex = (n/a|(?:##|[a-z]{2}) \d{4})\)$`)
-//
-//	// rxTribeFollows captures tribe follows lines.
-//	// these look like:
-//	// - tribe follows 0987g1
-//	rxTribeFollowsLine = regexp.MustCompile(`^tribe follows (\d{4}(?:[cdefg]\d)?)$`)
-//
-//	// rxTribeGoesTo captures tribe goes to lines.
-//	// these look like:
-//	// - tribe goes to QQ 0707
-//	rxTribeGoesToLine = regexp.MustCompile(`^tribe goes to ([a-z][a-z] \d{4})$`)
-//
-//	// rxTribeMovementLine captures tribe movement lines.
-//	// these look like:
-//	// - tribe movement:move
-//	// - tribe movement:move ne-pr\n-pr,o nw
-//	// 0987/data/input/0900-09.0987.report.txt:Tribe Movement: Move S-GH,  L NE,  SE,  S\SW-GH,  L SE,  S\SW-GH,  L SE,  S\SW-GH,  L SE,  S\SW-GH,  L SE,  S\SW-PR,  L SE\S-GH,  L NE, River SE S\No Ford on River to SE of HEX
-//	// - tribe movement:move nw-pr,river sw,ford s,dowdy holler,0987g1\not enough m.p's to move to n into swamp
-//	rxTribeMovementLine = regexp.MustCompile(`^tribe movement:move(.*)$`)
-//
-//	// rxTribeStatusLine captures tribe status lines.
-//	// these look like:
-//	// - unit status: terrain, settlement, resources, edges, neighboring-terrains, units, maybe-some-other-stuff
-//	// - 0987 status:grassy hills,dowdy holler,coal,river n ne,ford se s,0987,0987e1
-//	// - 0987g1 status:conifer hills,west harbor,iron ore,o ne,n,ford se,s,stone road ne n,0987g1
-//	rxTribeStatusLine = regexp.MustCompile(`\d{4}(?:[cdefg]\d)? status:(.*)$`)
-//
-//	// - current turn 900-04(#4),summer,fine
-//	rxTurnHeaderLine = regexp.MustCompile(`^current turn (\d{3,4})-(\d{1,2})`)
-//)
-//
-//// ToReport filters an input slice of lines, keeping only:
-//// - Unit headers
-//// - Turn headers
-//// - Movement lines
-//// - Unit status lines
-//// Returns a Report containing only the lines needed for mapping.
-//func ToReport(filename string, input [][]byte) *Report {
-//	report := &Report{
-//		FileName: filename,
-//		Units:    make(map[string]*Unit),
-//	}
-//	report.Meta.GeneratedBy = "tn3"
-//	report.Meta.Version = version.String()
-//	report.Meta.Timestamp = time.Now().UTC().Unix()
-//	unit := &Unit{}
-//	for n, line := range input {
-//		if match := rxTribeHeaderLine.FindSubmatch(line); match != nil {
-//			unit = &Unit{
-//				Id:   string(match[1]),
-//				From: string(match[3]),
-//				To:   string(match[2]),
-//			}
-//			report.Units[unit.Id] = unit
-//		} else if match := rxTribeHeaderMiscLine.FindSubmatch(line); match != nil {
-//			unit = &Unit{
-//				Id:   string(match[1]),
-//				Name: string(match[2]),
-//				From: string(match[4]),
-//				To:   string(match[3]),
-//			}
-//			report.Units[unit.Id] = unit
-//		} else if IsUnitHeader(line) {
-//			// this match seems redundant, but it's not.
-//			// it allows us to capture unit headers that are slightly off.
-//			// if we didn't, then it would be much harder for the players to debug their reports.
-//			unit = &Unit{
-//				Id:    fmt.Sprintf("unit-%03d", n+1),
-//				Input: string(line),
-//			}
-//			report.Units[unit.Id] = unit
-//		} else if match := rxTurnHeaderLine.FindSubmatch(line); match != nil {
-//			year, _ := strconv.Atoi(string(match[1]))
-//			month, _ := strconv.Atoi(string(match[2]))
-//			report.TurnId = fmt.Sprintf("%04d-%02d", year, month)
-//		} else if rxTurnHeader.Match(line) {
-//			// this match seems redundant, but it's not.
-//			// it allows us to capture turn headers that are slightly off.
-//			// if we didn't, then it would be much harder for the players to debug their reports.
-//			report.TurnId = string(line)
-//		} else if match := rxScoutPatrolLine.FindSubmatch(line); match != nil {
-//			scout := &Scout{
-//				Id: string(match[1]),
-//			}
-//			for _, step := range strings.Split(string(match[2]), "\\") {
-//				step = strings.TrimSpace(strings.TrimLeft(strings.TrimRight(step, ", "), ", "))
-//				if step == "" {
-//					continue
-//				}
-//				scout.Patrol = append(scout.Patrol, step)
-//			}
-//			unit.Scouts = append(unit.Scouts, scout)
-//		} else if match := rxTribeMovementLine.FindSubmatch(line); match != nil {
-//			for _, step := range strings.Split(string(match[1]), "\\") {
-//				if step = strings.TrimSpace(step); step == "" {
-//					continue
-//				}
-//				unit.Moves = append(unit.Moves, &Step{
-//					Step: step,
-//				})
-//			}
-//		} else if match := rxTribeFollowsLine.FindSubmatch(line); match != nil {
-//			unit.Moves = append(unit.Moves, &Step{Follows: string(match[1])})
-//		} else if match := rxTribeGoesToLine.FindSubmatch(line); match != nil {
-//			unit.Moves = append(unit.Moves, &Step{GoesTo: string(match[1])})
-//		} else if match := rxFleetMovementLine.FindSubmatch(line); match != nil {
-//			unit.Winds = &Winds{
-//				Strength:  string(match[1]),
-//				Direction: string(match[2]),
-//			}
-//			for _, step := range strings.Split(string(match[3]), "\\") {
-//				if step = strings.TrimSpace(step); step == "" {
-//					continue
-//				}
-//				fs := &Step{}
-//				if shtep, shobvs, ok := strings.Cut(step, "-("); !ok {
-//					fs.Step = step
-//				} else {
-//					fs.Step = strings.TrimSpace(strings.TrimRight(shtep, ","))
-//					fs.Observations = "(" + strings.TrimSpace(shobvs)
-//				}
-//				unit.Moves = append(unit.Moves, fs)
-//			}
-//		} else if match := rxTribeStatusLine.FindSubmatch(line); match != nil {
-//			unit.Status = string(match[1])
-//		}
-//	}
-//	return report
-//}
+var (
+	// rxFleetMovementLine captures fleet movement lines.
+	rxFleetMovementLine = regexp.MustCompile(`^(calm|mild|strong|gale) (ne|se|sw|nw|n|s) fleet movement:move(.*)$`)
+
+	// rxScoutPatrolLine captures scout patrol lines.
+	// the scout number is checked against the parser's limit.
+	// some reports use the plural or pad the number, so these look like:
+	// - scout 1:scout ne-gh
+	// - scouts 09:scouts ne-gh
+	rxScoutPatrolLine = regexp.MustCompile(`^scouts? 0*(\d+):scouts?(.*)$`)
+
+	// rxTurnHeaderLine is the regular expression that matches the turn header line.
+	// that line looks like: "tribe 0138,current hex = ## 0709,(previous hex = ## 0709)"
+	rxTribeHeaderLine     = regexp.MustCompile(`^(?:courier|element|garrison|fleet|tribe) (\d{4}(?:[cdefg]\d+)?),current hex = (n/a|(?:##|[a-z]{2}) \d{4}),\(previous hex = (n/a|(?:##|[a-z]{2}) \d{4})\)$`)
+	rxTribeHeaderMiscLine = regexp.MustCompile(`^(?:courier|element|garrison|fleet|tribe) (\d{4}(?:[cdefg]\d+)?),([^,]*),current hex = (n/a|(?:##|[a-z]{2}) \d{4}),\(previous hex = (n/a|(?:##|[a-z]{2}) \d{4})\)$`)
+
+	// rxHeaderHexes captures the text of the current and previous hexes from a unit header
+	// so that headerError can tell a malformed hex from a malformed unit id.
+	rxHeaderHexes = regexp.MustCompile(`current hex = ([^,]*),\(previous hex = ([^)]*)\)$`)
+	// rxHeaderHex matches a hex in a unit header.
+	rxHeaderHex = regexp.MustCompile(`^(?:n/a|(?:##|[a-z]{2}) \d{4})$`)
+
+	// rxTribeFollows captures tribe follows lines.
+	// these look like:
+	// - tribe follows 0987g1
+	rxTribeFollowsLine = regexp.MustCompile(`^tribe follows (\d{4}(?:[cdefg]\d+)?)$`)
+
+	// rxTribeGoesTo captures tribe goes to lines.
+	// these look like:
+	// - tribe goes to QQ 0707
+	// - tribe goes to QQ 0707, dowdy holler
+	// - tribe goes to QQ 0707 (3 hexes)
+	rxTribeGoesToLine = regexp.MustCompile(`^tribe goes to ([a-z][a-z] \d{4}) ?(?:\((\d+) hex(?:es)?\))?(?:,([^,]+))?$`)
+
+	// rxTribeMovementLine captures tribe movement lines.
+	// these look like:
+	// - tribe movement:move
+	// - tribe movement:move ne-pr\n-pr,o nw
+	// 0987/data/input/0900-09.0987.report.txt:Tribe Movement: Move S-GH,  L NE,  SE,  S\SW-GH,  L SE,  S\SW-GH,  L SE,  S\SW-GH,  L SE,  S\SW-GH,  L SE,  S\SW-PR,  L SE\S-GH,  L NE, River SE S\No Ford on River to SE of HEX
+	// - tribe movement:move nw-pr,river sw,ford s,dowdy holler,0987g1\not enough m.p's to move to n into swamp
+	rxTribeMovementLine = regexp.MustCompile(`^tribe movement:move(.*)$`)
+
+	// rxTribeStatusLine captures tribe status lines.
+	// these look like:
+	// - unit status: terrain, settlement, resources, edges, neighboring-terrains, units, maybe-some-other-stuff
+	// - 0987 status:grassy hills,dowdy holler,coal,river n ne,ford se s,0987,0987e1
+	// - 0987g1 status:conifer hills,west harbor,iron ore,o ne,n,ford se,s,stone road ne n,0987g1
+	rxTribeStatusLine = regexp.MustCompile(`\d{4}(?:[cdefg]\d+)? status:(.*)$`)
+
+	// rxScoutContinuationLine matches the continuation of a scout patrol that wrapped onto a new line.
+	// these look like:
+	// - \ne-gh,river se
+	// - ne-gh,nothing of interest found
+	rxScoutContinuationLine = regexp.MustCompile(`^(?:\\|(?:ne|se|sw|nw|n|s)-[a-z])`)
+
+	// rxShortHex captures a hex in a unit header that is missing the leading zero.
+	// these look like:
+	// - current hex = ## 709
+	// - (previous hex = qq 709)
+	rxShortHex = regexp.MustCompile(`(hex = (?:##|[a-z]{2}) )(\d{3})\b`)
+
+	// rxOrdersLine captures the summary of the orders given to a unit.
+	// these look like:
+	// - orders:move ne,ne,n
+	// - 0987e1 orders:scout n
+	rxOrdersLine = regexp.MustCompile(`^(?:\d{4}(?:[cdefg]\d+)? )?orders?:(.*)$`)
+
+	// rxTurnHeaderLine captures the turn id and turn number from the turn header.
+	// older reports may have only one of them.
+	// these look like:
+	// - current turn 900-04(#4),summer,fine
+	// - current turn 900-04,summer,fine
+	// - current turn #4,summer,fine
+	// - current turn(#4),summer,fine
+	rxTurnHeaderLine = regexp.MustCompile(`^current turn ?(?:(\d{3,4})-(\d{1,2}))?(?:\(#(\d+)\)|#(\d+))?`)
+)
+
+// ToReport filters an input slice of lines, keeping only:
+// - Unit headers
+// - Turn headers
+// - Movement lines
+// - Unit status lines
+// Returns a Report containing only the lines needed for mapping.
+// It uses a Parser with the default configuration.
+func ToReport(filename string, input [][]byte) *Report {
+	p, _ := NewParser()
+	return p.ToReport(filename, input)
+}
+
+// ToReport returns a Report containing only the lines needed for mapping.
+// The lines are expected to be in lower case unless the parser was created
+// with WithPreserveCase, in which case they are matched without regard to case.
+func (p *Parser) ToReport(filename string, input [][]byte) *Report {
+	started := time.Now()
+	var report *Report
+	if p.preserveCase {
+		casing := nameCasing{}
+		casing.addLines(input...)
+		report = p.toReport(filename, lowerLines(input))
+		casing.restore(report)
+	} else {
+		report = p.toReport(filename, input)
+	}
+	p.reportParsed(report, started)
+	return report
+}
+
+// toReport is ToReport without sending the timing and errors to the parser's metrics.
+// Each line is still counted with the metrics as it is classified.
+func (p *Parser) toReport(filename string, input [][]byte) *Report {
+	return p.toReportAt(filename, input, 0)
+}
+
+// toReportAt is toReport for lines that start after the first offset lines of the input,
+// as when a file holds more than one report. Line numbers in the report, and the ids
+// of units with headers that can't be parsed, count from the start of the whole input.
+func (p *Parser) toReportAt(filename string, input [][]byte, offset int) *Report {
+	report := p.newReport(filename)
+	unit := &Unit{}
+	// scout and scoutLine track the most recent scout line so that wrapped patrols can be joined
+	var scout *Scout
+	scoutLine := -1
+	legendStart, legend := findLegend(input)
+	report.Meta.Legend = legend
+	for n, line := range input {
+		if n >= legendStart {
+			p.metrics.LineClassified(LineLegend)
+			continue
+		}
+		kind, lineNo := LineUnknown, offset+n+1
+		line = p.normalizeHeader(report, lineNo, line)
+		if u, ok := unitFromHeader(line); ok {
+			kind = LineUnitHeader
+			unit = p.addUnit(report, lineNo, u)
+			if report.ClanId == "" {
+				report.ClanId = clanIdFromHeader(line)
+			}
+		} else if IsUnitHeader(line) {
+			// this match seems redundant, but it's not.
+			// it allows us to capture unit headers that are slightly off.
+			// if we didn't, then it would be much harder for the players to debug their reports.
+			kind = LineUnitHeader
+			unit = &Unit{
+				Id:    fmt.Sprintf("unit-%03d", lineNo),
+				Input: string(line),
+			}
+			report.Units[unit.Id] = unit
+			report.Errors = append(report.Errors, ReportError{Line: lineNo, Input: string(line), Err: headerError(line)})
+		} else if p.setTurn(report, lineNo, unit.Id, line) {
+			kind = LineTurnHeader
+		} else if rxTurnHeader.Match(line) {
+			// this match seems redundant, but it's not.
+			// it allows us to capture turn headers that are slightly off.
+			// if we didn't, then it would be much harder for the players to debug their reports.
+			kind = LineTurnHeader
+			if report.TurnId == "" {
+				report.TurnId = string(line)
+			}
+		} else if match := rxScoutPatrolLine.FindSubmatch(line); match != nil && p.IsScoutLine(line) {
+			kind = LineScout
+			scout = &Scout{
+				Id: string(match[1]),
+			}
+			scout.addSteps(string(match[2]))
+			unit.Scouts = append(unit.Scouts, scout)
+			scoutLine = n
+		} else if scout != nil && scoutLine == n-1 && rxScoutContinuationLine.Match(line) {
+			// a long patrol may wrap onto the next line in the Word document.
+			// only the line immediately after a scout line is treated as a continuation.
+			kind = LineScout
+			scout.addSteps(string(line))
+			scoutLine = n
+		} else if match := rxTribeMovementLine.FindSubmatch(line); match != nil {
+			kind = LineMovement
+			p.addMovement(report, lineNo, unit, string(match[1]))
+		} else if reason, ok := unitStillReason(string(line)); ok {
+			kind = LineStill
+			unit.Still, unit.StillReason = true, reason
+		} else if condition, effect, ok := weatherEffect(line); ok {
+			// notes before the first unit header apply to the whole turn
+			kind = LineWeather
+			report.WeatherEffects = append(report.WeatherEffects, WeatherEffect{
+				Line:      lineNo,
+				UnitId:    unit.Id,
+				Condition: condition,
+				Effect:    effect,
+			})
+		} else if match := rxTribeFollowsLine.FindSubmatch(line); match != nil {
+			kind = LineFollows
+			unit.Moves = append(unit.Moves, &Step{Kind: StepFollows, Follows: string(match[1])})
+		} else if match := rxTribeGoesToLine.FindSubmatch(line); match != nil {
+			kind = LineGoesTo
+			unit.Moves = append(unit.Moves, p.goesToStep(match))
+		} else if match := rxFleetMovementLine.FindSubmatch(line); match != nil {
+			kind = LineFleetMovement
+			p.addFleetMovement(unit, match)
+		} else if match := rxOrdersLine.FindSubmatch(line); match != nil {
+			kind = LineOrders
+			unit.Orders = strings.TrimSpace(string(match[1]))
+		} else if match := rxTribeStatusLine.FindSubmatch(line); match != nil {
+			kind = LineStatus
+			p.setStatus(report, lineNo, unit, string(match[1]))
+		} else if IsNoReport(line) {
+			kind = LineNoReport
+			unit.NoReport = true
+		} else if obs, ok := p.parseObservationLine(line); ok {
+			kind = LineObservation
+			unit.Observations = append(unit.Observations, obs)
+		} else if event, ok := parseEvent(line, unit.Id); ok {
+			kind = LineEvent
+			event.Line = lineNo
+			report.Events = append(report.Events, event)
+		} else if len(bytes.TrimSpace(line)) == 0 {
+			kind = LineBlank
+		} else if p.onUnknownLine != nil {
+			if unit.Id == "" {
+				p.onUnknownLine(lineNo, line, nil)
+			} else {
+				p.onUnknownLine(lineNo, line, unit)
+			}
+		}
+		p.metrics.LineClassified(kind)
+	}
+	report.collectSightings()
+	return report
+}
+
+// newReport returns an empty report for the file.
+// The map size is recorded so that the report can be validated against the same map.
+func (p *Parser) newReport(filename string) *Report {
+	report := &Report{
+		FileName: filename,
+		Units:    make(map[string]*Unit),
+	}
+	report.Meta.GeneratedBy = "tn3"
+	report.Meta.SchemaVersion = SchemaVersion
+	report.Meta.Version = version.String()
+	report.Meta.Timestamp = time.Now().UTC().Unix()
+	report.Meta.MapColumns, report.Meta.MapRows = p.mapColumns, p.mapRows
+	return report
+}
+
+// unitFromHeader returns a new unit from a unit header.
+// The header lists the current hex before the previous hex, so the first hex
+// captured is To and the second is From.
+// Returns false if the header isn't well-formed.
+func unitFromHeader(line []byte) (*Unit, bool) {
+	kind, _ := UnitKind(line)
+	if match := rxTribeHeaderLine.FindSubmatch(line); match != nil {
+		return &Unit{
+			Id:   string(match[1]),
+			Kind: kind,
+			From: string(match[3]),
+			To:   string(match[2]),
+		}, true
+	} else if match := rxTribeHeaderMiscLine.FindSubmatch(line); match != nil {
+		return &Unit{
+			Id:   string(match[1]),
+			Kind: kind,
+			Name: unquote(string(match[2])),
+			From: string(match[4]),
+			To:   string(match[3]),
+		}, true
+	}
+	return nil, false
+}
+
+// setTurn updates the report from a turn header.
+// Returns false if the line is not a turn header.
+func (p *Parser) setTurn(report *Report, line int, unitId string, text []byte) bool {
+	turnId, turnNumber, ok := parseTurnHeader(text)
+	if !ok {
+		return false
+	}
+	// every unit section repeats the turn header, and it may come before or after
+	// the unit headers. the first one wins so that a stray header from another
+	// turn can't change the turn for the units that have already been parsed.
+	// that goes for the turn number as well as the turn id.
+	if report.TurnId != "" && turnId != "" && turnId != report.TurnId {
+		report.Warnings = append(report.Warnings, Warning{
+			Line:    line,
+			UnitId:  unitId,
+			Message: fmt.Sprintf("turn %s conflicts with turn %s", turnId, report.TurnId),
+		})
+		return true
+	} else if report.TurnNumber != 0 && turnNumber != 0 && turnNumber != report.TurnNumber {
+		report.Warnings = append(report.Warnings, Warning{
+			Line:    line,
+			UnitId:  unitId,
+			Message: fmt.Sprintf("turn #%d conflicts with turn #%d", turnNumber, report.TurnNumber),
+		})
+		return true
+	}
+	// an older header may have only one of the turn id and number, so don't lose the other
+	if turnId != "" {
+		report.TurnId = turnId
+	}
+	if turnNumber != 0 {
+		report.TurnNumber = turnNumber
+	}
+	if season, weather := seasonAndWeather(text); season != "" {
+		report.Season, report.Weather = season, weather
+	}
+	if turnId != "" && turnNumber != 0 && turnNumberFromId(turnId) != turnNumber {
+		report.Warnings = append(report.Warnings, Warning{
+			Line:    line,
+			Message: fmt.Sprintf("turn %s is not turn #%d", turnId, turnNumber),
+		})
+	}
+	return true
+}
+
+// addMovement adds the steps from the text of a movement line (everything after "move") to the unit.
+func (p *Parser) addMovement(report *Report, line int, unit *Unit, text string) {
+	if reason, ok := unitStillReason(strings.TrimSpace(text)); ok {
+		unit.Still, unit.StillReason = true, reason
+		return
+	}
+	steps := p.parseMovement(text)
+	for _, step := range steps {
+		step.Kind = StepMove
+		for _, edge := range step.Edges {
+			p.checkSeason(report, line, unit.Id, edge.Seasonal)
+		}
+	}
+	unit.Moves = append(unit.Moves, steps...)
+}
+
+// addFleetMovement adds the steps from a fleet movement line to the unit.
+// A fleet may report movement across several wind phases, one line per phase.
+// The steps from each line are merged and each step records the wind for its phase.
+func (p *Parser) addFleetMovement(unit *Unit, match [][]byte) {
+	winds := &Winds{
+		Strength:  string(match[1]),
+		Direction: string(match[2]),
+	}
+	unit.Winds = winds
+	for _, step := range strings.Split(string(match[3]), "\\") {
+		if step = strings.TrimSpace(step); step == "" {
+			continue
+		}
+		var fs *Step
+		if shtep, shobvs, ok := strings.Cut(step, "-("); !ok {
+			fs = p.parseStep(step)
+		} else {
+			fs = p.parseStep(strings.TrimSpace(strings.TrimRight(shtep, ",")))
+			fs.Observations = "(" + strings.TrimSpace(shobvs)
+			fs.FleetObservations = p.fleetObservations(fs.Observations)
+		}
+		fs.Kind, fs.Winds = StepFleet, winds
+		unit.Moves = append(unit.Moves, fs)
+	}
+	// a fleet may backtrack across phases, so all of its moves are checked
+	markBacktracks(unit.Moves)
+}
+
+// goesToStep returns the step for a match of rxTribeGoesToLine.
+func (p *Parser) goesToStep(match [][]byte) *Step {
+	step := &Step{Kind: StepGoesTo, GoesTo: p.hex(string(match[1])), Settlement: unquote(strings.TrimSpace(string(match[3])))}
+	step.Distance, _ = strconv.Atoi(string(match[2]))
+	return step
+}
+
+// setStatus sets the unit's status from the text of a status line (everything after "status:").
+func (p *Parser) setStatus(report *Report, line int, unit *Unit, text string) {
+	unit.Status = p.parseStatus(text)
+	p.checkSeason(report, line, unit.Id, unit.Status.Seasonal)
+	unit.Population, unit.Warriors = statusCounts(unit.Status.Raw)
+	unit.Cargo = statusCargo(unit.Status.Raw)
+	if unit.Kind == "fleet" {
+		unit.Fleet = fleetManifest(unit.Status.Raw)
+	}
+}
+
+// addUnit adds a unit from a unit header to the report and returns the unit
+// that the lines following the header belong to.
+// If the report already has a unit with the same id, the parser's
+// DuplicateStrategy decides what happens to the new unit.
+func (p *Parser) addUnit(report *Report, line int, unit *Unit) *Unit {
+	unit.From, unit.To = p.hex(unit.From), p.hex(unit.To)
+	unit.setHexes()
+	unit.Stationary = isKnownHex(unit.From) && unit.From == unit.To
+	p.checkUnitHexes(report, line, unit)
+	prev, ok := report.Units[unit.Id]
+	if !ok {
+		report.Units[unit.Id] = unit
+		return unit
+	}
+	switch p.duplicates {
+	case DuplicateSuffix:
+		id := unit.Id
+		for k := 2; report.Units[unit.Id] != nil; k++ {
+			unit.Id = fmt.Sprintf("%s-%d", id, k)
+		}
+		report.Warnings = append(report.Warnings, Warning{Line: line, UnitId: id, Message: fmt.Sprintf("duplicate unit header: renamed to %s", unit.Id)})
+		report.Units[unit.Id] = unit
+		return unit
+	case DuplicateMerge:
+		report.Warnings = append(report.Warnings, Warning{Line: line, UnitId: unit.Id, Message: "duplicate unit header: merged"})
+		if prev.Name == "" {
+			prev.Name = unit.Name
+		}
+		// the later header is newer, so its hexes win when it has them
+		if isKnownHex(unit.From) {
+			prev.From, prev.FromInput = unit.From, unit.FromInput
+		}
+		if isKnownHex(unit.To) {
+			prev.To, prev.ToInput = unit.To, unit.ToInput
+		}
+		prev.setHexes()
+		prev.Stationary = isKnownHex(prev.From) && prev.From == prev.To
+		return prev
+	}
+	report.Warnings = append(report.Warnings, Warning{Line: line, UnitId: unit.Id, Message: "duplicate unit header: replaces earlier unit"})
+	report.Units[unit.Id] = unit
+	return unit
+}
+
+// headerError returns the reason a unit header couldn't be parsed.
+// A header is missing a field if it doesn't have both the current and previous hex,
+// and has an invalid hex if either hex isn't formatted like "qq 0709", "## 0709", or "n/a".
+// The name can't be checked since empty names are removed when spaces are compressed.
+func headerError(line []byte) Error {
+	if !bytes.Contains(line, []byte("current hex =")) || !bytes.Contains(line, []byte("previous hex =")) {
+		return ErrMissingField
+	}
+	if match := rxHeaderHexes.FindSubmatch(line); match != nil && (!rxHeaderHex.Match(match[1]) || !rxHeaderHex.Match(match[2])) {
+		return ErrInvalidHex
+	}
+	return ErrInvalidElementId
+}
+
+// normalizeHeader replaces descriptive grid names with grid codes and pads short hexes
+// in a unit header, adding a warning to the report for each padded header.
+// Lines that aren't unit headers are returned unchanged.
+func (p *Parser) normalizeHeader(report *Report, line int, header []byte) []byte {
+	if resolved, ok := p.resolveGridNames(header); ok {
+		header = resolved
+	}
+	if padded, ok := padShortHexes(header); ok {
+		report.Warnings = append(report.Warnings, Warning{
+			Line:    line,
+			Message: "hex is missing a leading zero: " + string(header),
+		})
+		header = padded
+	}
+	return header
+}
+
+// padShortHexes adds the missing leading zero to hexes in a unit header
+// that were written with three digits, like "## 709".
+// Returns the updated line and true if any hex was padded.
+// The input line is not modified.
+func padShortHexes(line []byte) ([]byte, bool) {
+	if !bytes.Contains(line, []byte("hex = ")) || !rxShortHex.Match(line) {
+		return line, false
+	}
+	return rxShortHex.ReplaceAll(line, []byte("${1}0${2}")), true
+}
+
+// checkSeason adds a warning to the report if a seasonal variant of a terrain
+// or edge was reported in a different season than the turn.
+// Nothing is checked if the variant or the turn's season is not known.
+func (p *Parser) checkSeason(report *Report, line int, unitId, seasonal string) {
+	if seasonal == "" || report.Season == "" {
+		return
+	} else if st, ok := p.vocabulary.Seasonal[seasonal]; ok && st.Season != report.Season {
+		report.Warnings = append(report.Warnings, Warning{
+			Line:    line,
+			UnitId:  unitId,
+			Message: fmt.Sprintf("%s reported in %s", seasonal, report.Season),
+		})
+	}
+}
+
+// checkUnitHexes adds a warning to the report for each of the unit's hexes that is not on the map.
+func (p *Parser) checkUnitHexes(report *Report, line int, unit *Unit) {
+	for _, hex := range []string{unit.From, unit.To} {
+		h, ok := parseHexCoordinate(hex)
+		if !ok {
+			continue
+		} else if err := p.CheckHex(h); err != nil {
+			report.Warnings = append(report.Warnings, Warning{Line: line, UnitId: unit.Id, Message: err.Error()})
+		}
+	}
+}
+
+// addSteps adds the backslash-separated steps from the text of a patrol to the scout.
+func (scout *Scout) addSteps(text string) {
+	for _, step := range strings.Split(text, "\\") {
+		step = strings.TrimSpace(strings.TrimLeft(strings.TrimRight(step, ", "), ", "))
+		if step == "" {
+			continue
+		} else if list, ok := directionList(step); ok {
+			// a patrol that only reports directions has one step per direction
+			for _, direction := range list {
+				scout.Patrol = append(scout.Patrol, direction)
+				scout.Steps = append(scout.Steps, &ScoutStep{Step: direction, Outcome: ScoutMoved, Direction: direction})
+			}
+			continue
+		}
+		scout.Patrol = append(scout.Patrol, step)
+		scout.Steps = append(scout.Steps, parseScoutStep(step))
+	}
+}
+
+var (
+	rxScoutBlocked   = regexp.MustCompile(`^(?:can't|cannot|can not|unable to) (?:move|scout).* to (ne|se|sw|nw|n|s) of hex`)
+	rxScoutDirection = regexp.MustCompile(`^(ne|se|sw|nw|n|s)(?:-([a-z]+))?$`)
+	rxScoutUnitId    = regexp.MustCompile(`^\d{4}(?:[cdefg]\d+)?$`)
+	rxScoutUnitIds   = regexp.MustCompile(`\b\d{4}(?:[cdefg]\d+)?\b`)
+)
+
+// parseScoutStep breaks a single step from a scout patrol into its outcome.
+// A step is either a move into a hex ("ne-gh,river se"), a note that the
+// scout could not enter a hex ("can't move on lake to n of hex"), or a note
+// that the scout found nothing ("nothing of interest found").
+// Units found by the scout are collected from any of those.
+func parseScoutStep(step string) *ScoutStep {
+	ss := &ScoutStep{Step: step, Outcome: ScoutMoved}
+	foundNothing := false
+	for n, field := range strings.Split(step, ",") {
+		field = strings.TrimSpace(field)
+		if match := rxScoutBlocked.FindStringSubmatch(field); match != nil {
+			ss.Outcome, ss.Direction, ss.Reason = ScoutBlocked, match[1], field
+		} else if strings.HasPrefix(field, "nothing of interest found") {
+			foundNothing = true
+		} else if strings.HasPrefix(field, "patrolled and found") {
+			ss.Units = append(ss.Units, rxScoutUnitIds.FindAllString(field, -1)...)
+		} else if rxScoutUnitId.MatchString(field) {
+			ss.Units = append(ss.Units, field)
+		} else if match := rxScoutDirection.FindStringSubmatch(field); n == 0 && match != nil {
+			ss.Direction, ss.Terrain = match[1], match[2]
+		}
+	}
+	// a scout that wasn't blocked and reports nothing of interest came up empty
+	if foundNothing && ss.Outcome != ScoutBlocked {
+		ss.Outcome = ScoutEmpty
+	}
+	return ss
+}
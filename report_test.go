@@ -0,0 +1,498 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/playbymail/tndocx"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReportTurnKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		clanId   string
+		turnId   string
+		expected string
+	}{
+		{name: "clan and turn", clanId: "0138", turnId: "0900-04", expected: "0138/0900-04"},
+		{name: "missing clan", clanId: "", turnId: "0900-04", expected: "unknown/0900-04"},
+		{name: "missing turn", clanId: "0138", turnId: "", expected: "0138/unknown"},
+		{name: "missing both", clanId: "", turnId: "", expected: "unknown/unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &tndocx.Report{ClanId: tt.clanId, TurnId: tt.turnId}
+			if got := r.TurnKey(); got != tt.expected {
+				t.Errorf("TurnKey() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseReportClanId(t *testing.T) {
+	sections := tndocx.SectionInput([]byte("element 2138e1,,current hex = ## 0709,(previous hex = ## 0709)\ntribe 2138,,current hex = ## 0709,(previous hex = ## 0709)\n"))
+	r, err := tndocx.ParseReport("0900-04.0138.report.txt", sections)
+	if err != nil {
+		t.Fatalf("ParseReport() error = %v", err)
+	}
+	if r.ClanId != "0138" {
+		t.Errorf("ClanId = %q, want %q", r.ClanId, "0138")
+	}
+}
+
+func TestParseReportDuplicateHeader(t *testing.T) {
+	sections := tndocx.SectionInput([]byte("tribe 0138,,current hex = qq 0709,(previous hex = qq 0708)\n" +
+		"tribe movement:move n-pr\n" +
+		"tribe 0138,,current hex = qq 0710,(previous hex = qq 0708)\n" +
+		"tribe movement:move ne-gh\\se-pr\n"))
+	p, err := tndocx.NewParser(tndocx.WithDuplicateStrategy(tndocx.DuplicateMerge))
+	if err != nil {
+		t.Fatalf("NewParser() error = %v", err)
+	}
+	r, err := p.ParseReport("0900-04.0138.report.txt", sections)
+	if err != nil {
+		t.Fatalf("ParseReport() error = %v", err)
+	}
+	unit := r.Units["0138"]
+	if got := len(unit.Moves); got != 3 {
+		t.Errorf("len(Moves) = %d, want 3", got)
+	}
+	if unit.From != "qq 0708" || unit.To != "qq 0710" || unit.CurrentHex.String() != "QQ 0710" {
+		t.Errorf("From, To, CurrentHex = %q, %q, %q, want the hexes from the later header", unit.From, unit.To, unit.CurrentHex)
+	}
+	if len(r.Warnings) != 1 || r.Warnings[0].UnitId != "0138" {
+		t.Errorf("Warnings = %+v, want one duplicate warning for 0138", r.Warnings)
+	}
+}
+
+func TestToReportQuotedName(t *testing.T) {
+	r := toReport(`Tribe 0138, "Dowdy Holler", Current Hex = QQ 0709, (Previous Hex = QQ 0708)`)
+	if unit, ok := r.Units["0138"]; !ok || unit.Name != "dowdy holler" {
+		t.Errorf("Name = %+v, want %q", unit, "dowdy holler")
+	}
+}
+
+func TestToReportScoutOutcomes(t *testing.T) {
+	input := [][]byte{
+		[]byte("tribe 0138,,current hex = ## 0709,(previous hex = ## 0709)"),
+		[]byte("scout 1:scout ne-gh\\n-pr,0138e1\\can't move on lake to n of hex"),
+		[]byte("scout 2:scout s-pr\\nothing of interest found"),
+	}
+	r := tndocx.ToReport("0900-04.0138.report.txt", input)
+	unit, ok := r.Units["0138"]
+	if !ok {
+		t.Fatalf("ToReport() did not return unit 0138")
+	} else if len(unit.Scouts) != 2 {
+		t.Fatalf("len(Scouts) = %d, want 2", len(unit.Scouts))
+	}
+
+	type step struct {
+		outcome   tndocx.ScoutOutcome
+		direction string
+		terrain   string
+		units     int
+	}
+	tests := []struct {
+		name  string
+		scout *tndocx.Scout
+		steps []step
+	}{
+		{name: "blocked", scout: unit.Scouts[0], steps: []step{
+			{outcome: tndocx.ScoutMoved, direction: "ne", terrain: "gh"},
+			{outcome: tndocx.ScoutMoved, direction: "n", terrain: "pr", units: 1},
+			{outcome: tndocx.ScoutBlocked, direction: "n"},
+		}},
+		{name: "empty", scout: unit.Scouts[1], steps: []step{
+			{outcome: tndocx.ScoutMoved, direction: "s", terrain: "pr"},
+			{outcome: tndocx.ScoutEmpty},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.scout.Steps) != len(tt.steps) {
+				t.Fatalf("len(Steps) = %d, want %d", len(tt.scout.Steps), len(tt.steps))
+			}
+			for i, want := range tt.steps {
+				got := tt.scout.Steps[i]
+				if got.Outcome != want.outcome {
+					t.Errorf("step %d: Outcome = %q, want %q", i+1, got.Outcome, want.outcome)
+				}
+				if got.Direction != want.direction {
+					t.Errorf("step %d: Direction = %q, want %q", i+1, got.Direction, want.direction)
+				}
+				if got.Terrain != want.terrain {
+					t.Errorf("step %d: Terrain = %q, want %q", i+1, got.Terrain, want.terrain)
+				}
+				if len(got.Units) != want.units {
+					t.Errorf("step %d: len(Units) = %d, want %d", i+1, len(got.Units), want.units)
+				}
+			}
+		})
+	}
+}
+
+func TestToReportScoutSpellings(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		`Scout 9:Scout NE-GH\N-PR`,
+		`Scouts 1:Scouts S-PR, Nothing of interest found`,
+		`Scout 02:Scout SW-PR`,
+	)
+	var ids []string
+	for _, scout := range r.Units["0138"].Scouts {
+		ids = append(ids, scout.Id)
+	}
+	if want := []string{"9", "1", "2"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Scouts = %q, want %q", ids, want)
+	}
+	if lines := tndocx.RemoveNonMappingLines([][]byte{[]byte("scouts 1:scouts s-pr")}); len(lines) != 1 {
+		t.Errorf("RemoveNonMappingLines() dropped the plural scout line")
+	}
+}
+
+func TestToReportOrders(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"Orders: Move NE, NE, N",
+		"Tribe Movement: Move NE-PR",
+		"0138 Status: PRAIRIE, 0138",
+		"Element 0138e1, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		"0138e1 Orders: Scout N",
+		"0138e1 Status: PRAIRIE, 0138e1",
+	)
+	tests := []struct {
+		unitId string
+		orders string
+		moves  int
+	}{
+		{unitId: "0138", orders: "move ne,ne,n", moves: 1},
+		{unitId: "0138e1", orders: "scout n", moves: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.unitId, func(t *testing.T) {
+			unit := r.Units[tt.unitId]
+			if unit.Orders != tt.orders {
+				t.Errorf("Orders = %q, want %q", unit.Orders, tt.orders)
+			}
+			if len(unit.Moves) != tt.moves {
+				t.Errorf("len(Moves) = %d, want %d", len(unit.Moves), tt.moves)
+			}
+			if unit.Status == nil || unit.Status.Terrain != "prairie" {
+				t.Errorf("Status = %+v, want terrain %q", unit.Status, "prairie")
+			}
+		})
+	}
+}
+
+func TestToReportStationary(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		"Courier 0138c1, , Current Hex = QQ 0710, (Previous Hex = QQ 0709)",
+		"Element 0138e1, , Current Hex = QQ 0709, (Previous Hex = N/A)",
+	)
+	for id, want := range map[string]bool{"0138": true, "0138c1": false, "0138e1": false} {
+		if got := r.Units[id].Stationary; got != want {
+			t.Errorf("%s: Stationary = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestToReportScoutContinuation(t *testing.T) {
+	input := [][]byte{
+		[]byte("tribe 0138,,current hex = ## 0709,(previous hex = ## 0709)"),
+		[]byte("scout 1:scout ne-gh\\n-pr"),
+		[]byte("\\nw-sw,river s\\n-pr"),
+		[]byte("se-gh,nothing of interest found"),
+		[]byte("tribe movement:move n-pr"),
+		[]byte("s-pr"),
+	}
+	r := tndocx.ToReport("0900-04.0138.report.txt", input)
+	unit := r.Units["0138"]
+	if len(unit.Scouts) != 1 {
+		t.Fatalf("len(Scouts) = %d, want 1", len(unit.Scouts))
+	}
+	want := []string{"ne-gh", "n-pr", "nw-sw,river s", "n-pr", "se-gh,nothing of interest found"}
+	if !reflect.DeepEqual(unit.Scouts[0].Patrol, want) {
+		t.Errorf("Patrol = %q, want %q", unit.Scouts[0].Patrol, want)
+	}
+	if len(unit.Scouts[0].Steps) != len(want) {
+		t.Errorf("len(Steps) = %d, want %d", len(unit.Scouts[0].Steps), len(want))
+	}
+}
+
+func TestToReportShortHex(t *testing.T) {
+	r := toReport("Tribe 0138, , Current Hex = ## 709, (Previous Hex = QQ 708)")
+	unit, ok := r.Units["0138"]
+	if !ok {
+		t.Fatalf("ToReport() did not return unit 0138")
+	}
+	if unit.To != "## 0709" || unit.From != "qq 0708" {
+		t.Errorf("From, To = %q, %q, want %q, %q", unit.From, unit.To, "qq 0708", "## 0709")
+	}
+	if len(r.Warnings) != 1 || r.Warnings[0].Line != 1 {
+		t.Errorf("Warnings = %+v, want one warning for line 1", r.Warnings)
+	}
+}
+
+func TestReportWalkUnits(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		"Element 0138e1, , Current Hex = QQ 0710, (Previous Hex = QQ 0709)",
+		"Courier 0138c1, , Current Hex = QQ 0711, (Previous Hex = QQ 0709)",
+	)
+	var visited []string
+	err := r.WalkUnits(func(unit *tndocx.Unit) error {
+		visited = append(visited, unit.Id)
+		unit.Name = "visited " + unit.Id
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkUnits() error = %v", err)
+	}
+	if want := []string{"0138", "0138c1", "0138e1"}; !reflect.DeepEqual(visited, want) {
+		t.Errorf("visited = %q, want %q", visited, want)
+	}
+	for id, unit := range r.Units {
+		if unit.Name != "visited "+id {
+			t.Errorf("%s: Name = %q, want %q", id, unit.Name, "visited "+id)
+		}
+	}
+
+	visited, stop := nil, errors.New("stop")
+	err = r.WalkUnits(func(unit *tndocx.Unit) error {
+		visited = append(visited, unit.Id)
+		if unit.Id == "0138c1" {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("WalkUnits() error = %v, want %v", err, stop)
+	}
+	if len(visited) != 2 {
+		t.Errorf("len(visited) = %d, want 2", len(visited))
+	}
+}
+
+func TestParseReportUnits(t *testing.T) {
+	sections, err := tndocx.ParseText([]byte(strings.Join([]string{
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"Current Turn 900-04 (#4), Summer, FINE",
+		`Tribe Movement: Move N-PR\NE-GH`,
+		"Scout 1:Scout N-GH,  Nothing of interest found",
+		"0138 Status: PRAIRIE, O NE, 0138",
+		"Courier 0138c1, Runner, Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		"Tribe Follows 0138",
+		"Element 0138e1, , Current Hex = Q 709, (Previous Hex = QQ 0709)",
+		"",
+	}, "\n")))
+	if err != nil {
+		t.Fatalf("ParseText() error = %v", err)
+	}
+	r, err := tndocx.ParseReport("0900-04.0138.report.txt", sections)
+	if err != nil {
+		t.Fatalf("ParseReport() error = %v", err)
+	}
+	if r.TurnId != "0900-04" || r.ClanId != "0138" {
+		t.Errorf("TurnId, ClanId = %q, %q, want %q, %q", r.TurnId, r.ClanId, "0900-04", "0138")
+	}
+
+	tribe := r.Units["0138"]
+	if tribe == nil {
+		t.Fatalf("ParseReport() did not return unit 0138")
+	}
+	if tribe.From != "qq 0708" || tribe.To != "qq 0709" {
+		t.Errorf("0138: From, To = %q, %q, want %q, %q", tribe.From, tribe.To, "qq 0708", "qq 0709")
+	}
+	if len(tribe.Moves) != 2 || tribe.Moves[1].Terrain != "gh" {
+		t.Errorf("0138: Moves = %+v, want 2 moves ending in gh", tribe.Moves)
+	}
+	if len(tribe.Scouts) != 1 || tribe.Scouts[0].Steps[0].Outcome != tndocx.ScoutEmpty {
+		t.Errorf("0138: Scouts = %+v, want 1 empty scout", tribe.Scouts)
+	}
+	if tribe.Status == nil || tribe.Status.Terrain != "prairie" {
+		t.Errorf("0138: Status = %+v, want prairie", tribe.Status)
+	}
+
+	courier := r.Units["0138c1"]
+	if courier == nil || courier.Name != "runner" || len(courier.Moves) != 1 || courier.Moves[0].Follows != "0138" {
+		t.Errorf("0138c1 = %+v, want runner following 0138", courier)
+	}
+
+	// the element's header isn't well-formed, so it's kept as raw input with an error
+	var bad *tndocx.Unit
+	for _, unit := range r.Units {
+		if unit.Input != "" {
+			bad = unit
+		}
+	}
+	if bad == nil || !strings.HasPrefix(bad.Input, "element 0138e1,") {
+		t.Fatalf("ParseReport() did not keep the malformed header")
+	}
+	if len(r.Errors) != 1 || r.Errors[0].Input != bad.Input || r.Errors[0].Err != tndocx.ErrInvalidHex {
+		t.Errorf("Errors = %+v, want invalid hex for %q", r.Errors, bad.Input)
+	}
+}
+
+func TestReportErrors(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		"0138 Status: PRAIRIE, 0138",
+	)
+	if r.Errors != nil {
+		t.Errorf("Errors = %+v, want nil", r.Errors)
+	}
+
+	r = toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		"Element 0138e1, , Current Hex = QQ 0709",
+		"Courier 0138c1, , Current Hex = QQ 0709, (Previous Hex = QQ)",
+		"Element 0138e2, , Current Hex = QQ 79, (Previous Hex = QQ 0709)",
+		"Element 0138e3, Big, Stone, Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+	)
+	if len(r.Errors) != 4 {
+		t.Fatalf("len(Errors) = %d, want 4", len(r.Errors))
+	}
+	for i, want := range []struct {
+		line int
+		err  error
+		text string
+	}{
+		{2, tndocx.ErrMissingField, "line 2: missing field"},
+		{3, tndocx.ErrInvalidHex, "line 3: invalid hex"},
+		{4, tndocx.ErrInvalidHex, "line 4: invalid hex"},
+		{5, tndocx.ErrInvalidElementId, "line 5: invalid element id"},
+	} {
+		got := r.Errors[i]
+		if got.Line != want.line || !errors.Is(got, want.err) || got.Error() != want.text {
+			t.Errorf("error %d = %+v (%q), want %q", i+1, got, got.Error(), want.text)
+		}
+	}
+}
+
+func TestReportNoReport(t *testing.T) {
+	input := "Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)\n" +
+		"0138 Status: PRAIRIE, 0138\n" +
+		"Element 0138e1, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)\n" +
+		"No Report\n"
+
+	r := toReport(strings.Split(input, "\n")...)
+	if r.Units["0138"].NoReport {
+		t.Errorf("0138: NoReport = true, want false")
+	}
+	if !r.Units["0138e1"].NoReport {
+		t.Errorf("0138e1: NoReport = false, want true")
+	}
+
+	sections, err := tndocx.ParseText([]byte(input))
+	if err != nil {
+		t.Fatalf("ParseText() error = %v", err)
+	}
+	r, err = tndocx.ParseReport("test", sections)
+	if err != nil {
+		t.Fatalf("ParseReport() error = %v", err)
+	}
+	if !r.Units["0138e1"].NoReport {
+		t.Errorf("ParseReport: 0138e1: NoReport = false, want true")
+	}
+}
+
+func TestUnitHexes(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"Element 0138e1, , Current Hex = ## 0710, (Previous Hex = N/A)",
+	)
+	for _, tt := range []struct {
+		id                string
+		current, previous tndocx.HexCoordinate
+	}{
+		{id: "0138", current: tndocx.HexCoordinate{Grid: "qq", Column: 7, Row: 9}, previous: tndocx.HexCoordinate{Grid: "qq", Column: 7, Row: 8}},
+		{id: "0138e1", current: tndocx.HexCoordinate{Column: 7, Row: 10, Obscured: true}},
+	} {
+		unit := r.Units[tt.id]
+		if unit.CurrentHex != tt.current || unit.PreviousHex != tt.previous {
+			t.Errorf("%s: CurrentHex, PreviousHex = %v, %v, want %v, %v", tt.id, unit.CurrentHex, unit.PreviousHex, tt.current, tt.previous)
+		}
+	}
+
+	p, err := tndocx.NewParser(tndocx.WithCanonicalHexes())
+	if err != nil {
+		t.Fatalf("NewParser() error = %v", err)
+	}
+	r = p.ToReport("test", [][]byte{[]byte("tribe 0138,,current hex = qq 0709,(previous hex = qq 0708)")})
+	if unit := r.Units["0138"]; unit.To != "QQ 0709" || unit.CurrentHex.String() != unit.To {
+		t.Errorf("To, CurrentHex = %q, %v, want %q for both", unit.To, unit.CurrentHex, "QQ 0709")
+	}
+}
+
+func TestUnitHeaderDirectionOfTravel(t *testing.T) {
+	for _, header := range []string{
+		"Tribe 0138, , Current Hex = QQ 0710, (Previous Hex = QQ 0709)",
+		"Tribe 0138, Dowdy Holler, Current Hex = QQ 0710, (Previous Hex = QQ 0709)",
+	} {
+		r := toReport(header, "Tribe Movement: Move S-PR")
+		unit := r.Units["0138"]
+		if unit.From != "qq 0709" || unit.To != "qq 0710" {
+			t.Errorf("%q: From, To = %q, %q, want %q, %q", header, unit.From, unit.To, "qq 0709", "qq 0710")
+		}
+		// the unit moved south from its previous hex to its current hex
+		if path := unit.Path(false); len(path) != 2 || path[0] != unit.PreviousHex || path[1] != unit.CurrentHex {
+			t.Errorf("%q: Path() = %v, want [%v %v]", header, path, unit.PreviousHex, unit.CurrentHex)
+		}
+	}
+}
+
+func TestUnitHeaderMixedObscuredHexes(t *testing.T) {
+	p, err := tndocx.NewParser(tndocx.WithCanonicalHexes())
+	if err != nil {
+		t.Fatalf("NewParser() error = %v", err)
+	}
+	for _, tt := range []struct {
+		header   string
+		from, to string
+		json     string
+	}{
+		{
+			header: "tribe 0138,,current hex = ## 0709,(previous hex = qq 0708)",
+			from:   "QQ 0708", to: "## 0709",
+			json: `"current-hex":"## 0709","previous-hex":"QQ 0708"`,
+		},
+		{
+			header: "tribe 0138,,current hex = qq 0709,(previous hex = ## 0708)",
+			from:   "## 0708", to: "QQ 0709",
+			json: `"current-hex":"QQ 0709","previous-hex":"## 0708"`,
+		},
+		{
+			header: "tribe 0138,,current hex = ## 0709,(previous hex = ## 0708)",
+			from:   "## 0708", to: "## 0709",
+			json: `"current-hex":"## 0709","previous-hex":"## 0708"`,
+		},
+		{
+			header: "tribe 0138,,current hex = ## 0709,(previous hex = n/a)",
+			from:   "n/a", to: "## 0709",
+			json: `"current-hex":"## 0709","previous-hex":"n/a"`,
+		},
+	} {
+		r := p.ToReport("test", [][]byte{[]byte(tt.header)})
+		unit, ok := r.Units["0138"]
+		if !ok {
+			t.Errorf("%q: unit not found", tt.header)
+			continue
+		}
+		if unit.From != tt.from || unit.To != tt.to {
+			t.Errorf("%q: From, To = %q, %q, want %q, %q", tt.header, unit.From, unit.To, tt.from, tt.to)
+		}
+		data, err := json.Marshal(unit)
+		if err != nil {
+			t.Fatalf("%q: json.Marshal() error = %v", tt.header, err)
+		}
+		if !strings.Contains(string(data), tt.json) {
+			t.Errorf("%q: json = %s, want it to contain %s", tt.header, data, tt.json)
+		}
+	}
+}
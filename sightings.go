@@ -0,0 +1,81 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+// Sighting is a report by one unit that it saw another.
+// Hex is where the other unit was seen, in canonical form. It is empty if the hex isn't known,
+// for example when a scout's path can't be followed from its unit's hex.
+type Sighting struct {
+	ObserverId string `json:"observer-id"`
+	Hex        string `json:"hex,omitempty"`
+	TurnId     string `json:"turn-id,omitempty"`
+}
+
+// collectSightings sets the report's sightings from the units found while moving,
+// the units found by scouts, and the units listed in each unit's status line.
+// Units don't sight themselves, and a unit seen more than once by the same
+// observer in the same hex is only recorded once.
+// Observers are visited in order of unit id so that the output is deterministic.
+func (r *Report) collectSightings() {
+	r.Sightings = nil
+	seen := map[Sighting]map[string]bool{}
+	add := func(unitId string, sighting Sighting) {
+		if unitId == sighting.ObserverId || seen[sighting][unitId] {
+			return
+		}
+		if seen[sighting] == nil {
+			seen[sighting] = map[string]bool{}
+		}
+		seen[sighting][unitId] = true
+		if r.Sightings == nil {
+			r.Sightings = map[string][]Sighting{}
+		}
+		r.Sightings[unitId] = append(r.Sightings[unitId], sighting)
+	}
+	for _, id := range r.sortedUnitIds() {
+		unit := r.Units[id]
+		if unit.Status != nil {
+			for _, unitId := range unit.Status.Units {
+				add(unitId, Sighting{ObserverId: unit.Id, Hex: canonicalHex(unit.To), TurnId: r.TurnId})
+			}
+		}
+		// units found while moving were seen in the hex the step entered
+		hex, known := parseHexCoordinate(unit.From)
+		for _, step := range unit.Moves {
+			if step.Kind != StepMove || step.Still {
+				continue
+			} else if known {
+				hex, known = hex.Neighbor(step.Direction)
+			}
+			sighting := Sighting{ObserverId: unit.Id, TurnId: r.TurnId}
+			if known {
+				sighting.Hex = hex.String()
+			}
+			for _, unitId := range step.Units {
+				add(unitId, sighting)
+			}
+		}
+		for _, scout := range unit.Scouts {
+			// scouts start from the hex the unit ended the turn in
+			hex, known := parseHexCoordinate(unit.To)
+			for _, step := range scout.Steps {
+				if known && step.Outcome != ScoutBlocked && step.Direction != "" {
+					hex, known = hex.Neighbor(step.Direction)
+				}
+				sighting := Sighting{ObserverId: unit.Id, TurnId: r.TurnId}
+				if known {
+					sighting.Hex = hex.String()
+				}
+				for _, unitId := range step.Units {
+					add(unitId, sighting)
+				}
+			}
+		}
+	}
+}
+
+// canonicalHex returns the canonical form of the hex, or an empty string if it isn't known.
+func canonicalHex(hex string) string {
+	canonical, _ := CanonicalHex(hex)
+	return canonical
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"github.com/playbymail/tndocx"
+	"reflect"
+	"testing"
+)
+
+func TestReportSightings(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		"Current Turn 900-04 (#4), Summer, FINE",
+		`Scout 1:Scout NE-PR, 0250\SE-GH, Patrolled and found 0250e1, 0138c1`,
+		`Scout 2:Scout Can't Move on Lake to N of HEX, 0250`,
+		"0138 Status: PRAIRIE, 0138, 0250",
+		"Courier 0138c1, , Current Hex = QQ 0909, (Previous Hex = QQ 0709)",
+		`Tribe Movement: Move NE-PR, 0987\SE-PR`,
+		"0138c1 Status: GRASSY HILLS, 0138c1, 0250e1",
+	)
+	want := map[string][]tndocx.Sighting{
+		"0250": {
+			{ObserverId: "0138", Hex: "QQ 0709", TurnId: "0900-04"},
+			{ObserverId: "0138", Hex: "QQ 0808", TurnId: "0900-04"},
+			// scout 2 was blocked in the unit's hex, where the status already saw 0250
+		},
+		"0250e1": {
+			{ObserverId: "0138", Hex: "QQ 0909", TurnId: "0900-04"},
+			{ObserverId: "0138c1", Hex: "QQ 0909", TurnId: "0900-04"},
+		},
+		"0987": {
+			{ObserverId: "0138c1", Hex: "QQ 0808", TurnId: "0900-04"},
+		},
+		"0138c1": {
+			{ObserverId: "0138", Hex: "QQ 0909", TurnId: "0900-04"},
+		},
+	}
+	if !reflect.DeepEqual(r.Sightings, want) {
+		t.Errorf("Sightings = %+v, want %+v", r.Sightings, want)
+	}
+}
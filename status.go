@@ -0,0 +1,280 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Status is the parsed status line for a unit.
+// Raw is the text of the line after "status:" and is kept for debugging.
+type Status struct {
+	Raw         string   `json:"raw,omitempty"`
+	Terrain     string   `json:"terrain,omitempty"`
+	TerrainCode string   `json:"terrain-code,omitempty"`
+	Seasonal    string   `json:"seasonal,omitempty"` // seasonal variant reported, like "frozen lake"
+	Settlement  string   `json:"settlement,omitempty"`
+	Resources   []string `json:"resources,omitempty"`
+	Edges       []*Edge  `json:"edges,omitempty"`
+	// NeighboringTerrains are the terrains seen in the hexes next to the unit.
+	NeighboringTerrains []*NeighboringTerrain `json:"neighboring-terrains,omitempty"`
+	// Units are the ids of the units in the hex, including the unit itself.
+	Units []string `json:"units,omitempty"`
+}
+
+// Edge is a feature that applies to one or more edges of a hex.
+// For example, "o ne n" is an ocean on the north-east and north edges.
+// Impassable is set when the report says the edge can't be crossed.
+type Edge struct {
+	Type       string   `json:"type"`
+	Directions []string `json:"directions,omitempty"`
+	Impassable bool     `json:"impassable,omitempty"`
+	Seasonal   string   `json:"seasonal,omitempty"` // seasonal variant reported, like "dry riverbed"
+}
+
+var (
+	// waterEdges maps the water terrain codes to the type of the edge.
+	// fleets distinguish deep from shallow ocean since it affects their movement.
+	// These are not taken from the parser's vocabulary, so a variant that renames
+	// or adds water terrains won't have them reported as edges.
+	waterEdges = map[string]string{
+		"do": "deep ocean",
+		"l":  "lake",
+		"o":  "ocean",
+		"so": "shallow ocean",
+	}
+
+	// directions is the set of direction codes used on the hex map.
+	directions = map[string]bool{
+		"n": true, "ne": true, "se": true, "s": true, "sw": true, "nw": true,
+	}
+)
+
+// parseStatus parses the text of a status line (everything after "status:").
+// The first segment is the terrain, which is looked up in the parser's vocabulary.
+// The second segment is the name of the settlement, if it isn't anything else.
+// Quotes around the name are removed.
+// The remaining segments are edges, terrains in neighboring hexes, resources, and
+// the ids of the units in the hex. Segments are separated by commas, except for
+// commas inside parentheses, which are part of the segment.
+// The directions for an edge or neighboring terrain may be separated by spaces
+// ("o ne n") or by commas ("o ne,n"), so bare directions are added to the preceding one.
+func (p *Parser) parseStatus(raw string) *Status {
+	status := &Status{Raw: raw}
+	var edge *Edge
+	var neighbor *NeighboringTerrain
+	for n, segment := range splitOutsideParens(raw) {
+		fields := strings.Fields(segment)
+		if n == 0 {
+			// the first segment is always the terrain
+			status.Terrain = strings.Join(fields, " ")
+			if seasonal, ok := p.vocabulary.Seasonal[status.Terrain]; ok {
+				status.Terrain, status.Seasonal = seasonal.Base, status.Terrain
+			}
+			status.TerrainCode, _ = p.vocabulary.TerrainCode(status.Terrain)
+			continue
+		} else if len(fields) == 0 {
+			edge, neighbor = nil, nil
+			continue
+		}
+		text := strings.Join(fields, " ")
+		if e, ok := p.parseEdge(fields); ok {
+			edge, neighbor = e, nil
+			status.Edges = append(status.Edges, edge)
+		} else if nt, ok := p.neighboringTerrain(fields); ok {
+			// checked before the directions since "sw" is both a terrain and a direction
+			edge, neighbor = nil, nt
+			status.NeighboringTerrains = append(status.NeighboringTerrains, neighbor)
+		} else if edge != nil && isDirectionList(fields) {
+			edge.Directions = append(edge.Directions, fields...)
+		} else if neighbor != nil && isDirectionList(fields) {
+			neighbor.Directions = append(neighbor.Directions, fields...)
+		} else if rxScoutUnitId.MatchString(text) {
+			edge, neighbor = nil, nil
+			status.Units = append(status.Units, text)
+		} else if p.vocabulary.Resources[text] {
+			edge, neighbor = nil, nil
+			status.Resources = append(status.Resources, text)
+		} else {
+			edge, neighbor = nil, nil
+			if n == 1 && !rxPopulation.MatchString(text) && !rxWarriors.MatchString(text) && !p.isStatusFeature(fields) {
+				status.Settlement = settlementName(unquote(text))
+			}
+		}
+	}
+	return status
+}
+
+// settlementName puts back the spaces that compressing the line removed from the details
+// in parentheses after a settlement's name, so "arcadia(port,market)" is returned as
+// "arcadia (port, market)".
+func settlementName(name string) string {
+	before, details, ok := strings.Cut(name, "(")
+	if !ok {
+		return name
+	}
+	return strings.TrimSpace(before) + " (" + strings.ReplaceAll(details, ",", ", ")
+}
+
+// NeighboringTerrain is a terrain that the unit can see in the hexes next to it,
+// like "lcm ne n" for low conifer mountains to the north-east and north.
+type NeighboringTerrain struct {
+	Terrain    string   `json:"terrain"`
+	Directions []string `json:"directions"`
+}
+
+// neighboringTerrain returns the neighboring terrain if the fields are a terrain
+// followed by one or more directions. The terrain may be a code or a name.
+// The first field is always part of the terrain, so "sw ne" is swamp to the north-east.
+func (p *Parser) neighboringTerrain(fields []string) (*NeighboringTerrain, bool) {
+	k := 0
+	for k < len(fields)-1 && directions[fields[len(fields)-1-k]] {
+		k++
+	}
+	if k == 0 {
+		return nil, false
+	}
+	name, dirs := strings.Join(fields[:len(fields)-k], " "), fields[len(fields)-k:]
+	if _, ok := p.vocabulary.Terrains[name]; ok {
+		return &NeighboringTerrain{Terrain: name, Directions: dirs}, true
+	} else if code, ok := p.vocabulary.TerrainCode(name); ok {
+		return &NeighboringTerrain{Terrain: code, Directions: dirs}, true
+	}
+	return nil, false
+}
+
+// isStatusFeature returns true if the fields are an edge from the vocabulary or a
+// fleet manifest, which can't be the name of a settlement.
+func (p *Parser) isStatusFeature(fields []string) bool {
+	if _, ok := p.parseEdge(fields); ok {
+		return true
+	}
+	text := strings.Join(fields, " ")
+	return rxFleetCarrying.MatchString(text) || rxFleetCargo.MatchString(text)
+}
+
+// splitOutsideParens splits the text at commas that are not inside parentheses.
+func splitOutsideParens(text string) (segments []string) {
+	depth, start := 0, 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				segments = append(segments, text[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(segments, text[start:])
+}
+
+// removeParens returns the text with the parenthesized parts, including nested ones, removed.
+// An unclosed parenthesis removes the rest of the text.
+func removeParens(text string) string {
+	var sb strings.Builder
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		switch ch := text[i]; {
+		case ch == '(':
+			depth++
+		case ch == ')' && depth > 0:
+			depth--
+		case depth == 0:
+			sb.WriteByte(ch)
+		}
+	}
+	return sb.String()
+}
+
+// isDirectionList returns true if every field is a direction code.
+// An empty list is not a direction list.
+func isDirectionList(fields []string) bool {
+	if len(fields) == 0 {
+		return false
+	}
+	for _, field := range fields {
+		if !directions[field] {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	// rxPopulation and rxWarriors capture labeled counts from a status line.
+	// the label may come before or after the number ("population 1200" or "1200 people").
+	// a bare number is ignored since it can't be told apart from a unit id or a resource quantity.
+	rxPopulation = regexp.MustCompile(`^(?:population|people) (\d+)$|^(\d+) (?:population|people)$`)
+	rxWarriors   = regexp.MustCompile(`^warriors (\d+)$|^(\d+) warriors$`)
+)
+
+// statusCounts returns the population and warrior counts from the text of a status line.
+// Counts that aren't reported are returned as zero.
+func statusCounts(raw string) (population, warriors int) {
+	for _, segment := range strings.Split(raw, ",") {
+		segment = strings.TrimSpace(segment)
+		if n, ok := labeledCount(rxPopulation, segment); ok {
+			population = n
+		} else if n, ok = labeledCount(rxWarriors, segment); ok {
+			warriors = n
+		}
+	}
+	return population, warriors
+}
+
+// labeledCount returns the number captured by either group of the regular expression.
+func labeledCount(rx *regexp.Regexp, segment string) (int, bool) {
+	match := rx.FindStringSubmatch(segment)
+	if match == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(match[1] + match[2])
+	return n, err == nil
+}
+
+// CargoItem is goods reported on a status line, like "5 grain".
+// Quantity is zero when the line doesn't give one.
+type CargoItem struct {
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity,omitempty"`
+}
+
+// rxCargoItem captures the quantity and name of a cargo item, like "5 grain" or "2 horses".
+var rxCargoItem = regexp.MustCompile(`^(\d+) (.+)$`)
+
+// statusCargo returns the goods that follow the unit list on a status line.
+// Every segment after the last unit id is an item, so names that aren't
+// known to the parser are kept. Labeled counts and fleet manifests are skipped.
+// Returns nil if the status doesn't have a unit list or nothing follows it.
+func statusCargo(raw string) (cargo []CargoItem) {
+	segments := splitOutsideParens(raw)
+	last := -1
+	for n, segment := range segments {
+		if rxScoutUnitId.MatchString(strings.TrimSpace(segment)) {
+			last = n
+		}
+	}
+	if last == -1 {
+		return nil
+	}
+	for _, segment := range segments[last+1:] {
+		text := strings.Join(strings.Fields(segment), " ")
+		if text == "" || rxPopulation.MatchString(text) || rxWarriors.MatchString(text) || rxFleetCarrying.MatchString(text) || rxFleetCargo.MatchString(text) {
+			continue
+		} else if match := rxCargoItem.FindStringSubmatch(text); match != nil {
+			quantity, _ := strconv.Atoi(match[1])
+			cargo = append(cargo, CargoItem{Name: match[2], Quantity: quantity})
+		} else {
+			cargo = append(cargo, CargoItem{Name: text})
+		}
+	}
+	return cargo
+}
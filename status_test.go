@@ -0,0 +1,271 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"github.com/playbymail/tndocx"
+	"reflect"
+	"testing"
+)
+
+func TestStatusWaterEdges(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []*tndocx.Edge
+	}{
+		{
+			name:     "single ocean",
+			input:    "0138 status:prairie,o ne,0138",
+			expected: []*tndocx.Edge{{Type: "ocean", Directions: []string{"ne"}}},
+		},
+		{
+			name:  "ocean directions separated by commas",
+			input: "0138 status:conifer hills,west harbor,iron ore,o ne,n,ford se,s,0138",
+			expected: []*tndocx.Edge{
+				{Type: "ocean", Directions: []string{"ne", "n"}},
+				{Type: "ford", Directions: []string{"se", "s"}},
+			},
+		},
+		{
+			name:  "named edges",
+			input: "0138 status:prairie,river n ne,stone road ne,n,pass sw",
+			expected: []*tndocx.Edge{
+				{Type: "river", Directions: []string{"n", "ne"}},
+				{Type: "stone road", Directions: []string{"ne", "n"}},
+				{Type: "pass", Directions: []string{"sw"}},
+			},
+		},
+		{
+			name:     "ocean directions separated by spaces",
+			input:    "0138 status:prairie,o ne n se",
+			expected: []*tndocx.Edge{{Type: "ocean", Directions: []string{"ne", "n", "se"}}},
+		},
+		{
+			name:  "ocean and lake",
+			input: "0138 status:prairie,o ne,n,l s",
+			expected: []*tndocx.Edge{
+				{Type: "ocean", Directions: []string{"ne", "n"}},
+				{Type: "lake", Directions: []string{"s"}},
+			},
+		},
+		{
+			name:  "no edges",
+			input: "0138 status:prairie,0138",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tndocx.ToReport("test", [][]byte{
+				[]byte("tribe 0138,,current hex = ## 0709,(previous hex = ## 0709)"),
+				[]byte(tt.input),
+			})
+			status := r.Units["0138"].Status
+			if status == nil {
+				t.Fatalf("Status = nil, want status")
+			}
+			if !reflect.DeepEqual(status.Edges, tt.expected) {
+				t.Errorf("Edges = %s, want %s", edgesString(status.Edges), edgesString(tt.expected))
+			}
+		})
+	}
+}
+
+func edgesString(edges []*tndocx.Edge) string {
+	s := "["
+	for i, edge := range edges {
+		if i != 0 {
+			s += " "
+		}
+		s += edge.Type
+		if edge.Impassable {
+			s += "!"
+		}
+		for _, d := range edge.Directions {
+			s += ":" + d
+		}
+	}
+	return s + "]"
+}
+
+func TestStatusPopulation(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		population int
+		warriors   int
+	}{
+		{name: "label first", input: "0138 Status: PRAIRIE, Population 1200, Warriors 250, 0138", population: 1200, warriors: 250},
+		{name: "label last", input: "0138 Status: PRAIRIE, 950 People, 75 Warriors, 0138", population: 950, warriors: 75},
+		{name: "resources and unit ids", input: "0138 Status: PRAIRIE, 2 horses, 0138, 1138", population: 0, warriors: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := toReport("Tribe 0138, , Current Hex = ## 0709, (Previous Hex = ## 0709)", tt.input)
+			unit := r.Units["0138"]
+			if unit.Population != tt.population {
+				t.Errorf("Population = %d, want %d", unit.Population, tt.population)
+			}
+			if unit.Warriors != tt.warriors {
+				t.Errorf("Warriors = %d, want %d", unit.Warriors, tt.warriors)
+			}
+		})
+	}
+}
+
+func TestStatusCargo(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		cargo []tndocx.CargoItem
+	}{
+		{
+			name:  "quantities",
+			input: "0138 Status: PRAIRIE, 0138, 0138e1, 2 horses, 5 grain",
+			cargo: []tndocx.CargoItem{{Name: "horses", Quantity: 2}, {Name: "grain", Quantity: 5}},
+		},
+		{
+			name:  "missing quantity and unknown item",
+			input: "0138 Status: PRAIRIE, 0138, Wagons, 3 Glass Beads, 75 Warriors",
+			cargo: []tndocx.CargoItem{{Name: "wagons"}, {Name: "glass beads", Quantity: 3}},
+		},
+		{
+			name:  "nothing after the units",
+			input: "0138 Status: PRAIRIE, 2 horses, 0138",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := toReport("Tribe 0138, , Current Hex = ## 0709, (Previous Hex = ## 0709)", tt.input)
+			if got := r.Units["0138"].Cargo; !reflect.DeepEqual(got, tt.cargo) {
+				t.Errorf("Cargo = %+v, want %+v", got, tt.cargo)
+			}
+		})
+	}
+}
+
+func TestStatusWaterDepth(t *testing.T) {
+	r := toReport(
+		"Fleet 0138f1, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"CALM NE Fleet Movement: Move NE-SO,-(NE DO, N O)\\N-DO",
+		"0138f1 Status: SHALLOW OCEAN, DO NE, N, SO S, 0138f1",
+	)
+	unit := r.Units["0138f1"]
+	if unit.Status.TerrainCode != "so" {
+		t.Errorf("TerrainCode = %q, want %q", unit.Status.TerrainCode, "so")
+	}
+	want := []*tndocx.Edge{
+		{Type: "deep ocean", Directions: []string{"ne", "n"}},
+		{Type: "shallow ocean", Directions: []string{"s"}},
+	}
+	if !reflect.DeepEqual(unit.Status.Edges, want) {
+		t.Errorf("Edges = %s, want %s", edgesString(unit.Status.Edges), edgesString(want))
+	}
+	if len(unit.Moves) != 2 {
+		t.Fatalf("len(Moves) = %d, want 2", len(unit.Moves))
+	}
+	for i, terrain := range []string{"so", "do"} {
+		if unit.Moves[i].Terrain != terrain {
+			t.Errorf("move %d: Terrain = %q, want %q", i+1, unit.Moves[i].Terrain, terrain)
+		}
+	}
+}
+
+func TestFleetManifest(t *testing.T) {
+	r := toReport(
+		"Fleet 0138f1, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"0138f1 Status: OCEAN, O NE, Carrying 0138e1 0138c1, Cargo 100 Grain 20 Wood, 0138f1, 0250",
+	)
+	fleet := r.Units["0138f1"].Fleet
+	if fleet == nil {
+		t.Fatalf("Fleet = nil, want manifest")
+	}
+	if want := []string{"0138e1", "0138c1"}; !reflect.DeepEqual(fleet.Carrying, want) {
+		t.Errorf("Carrying = %q, want %q", fleet.Carrying, want)
+	}
+	if want := []string{"100 grain", "20 wood"}; !reflect.DeepEqual(fleet.Cargo, want) {
+		t.Errorf("Cargo = %q, want %q", fleet.Cargo, want)
+	}
+
+	r = toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"0138 Status: PRAIRIE, O NE, 0138, 0250",
+	)
+	if r.Units["0138"].Fleet != nil {
+		t.Errorf("Fleet = %+v, want nil for a tribe", r.Units["0138"].Fleet)
+	}
+}
+
+func TestStatusFields(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		settlement string
+		resources  []string
+		neighbors  []tndocx.NeighboringTerrain
+		units      []string
+	}{
+		{
+			name:       "settlement and resources",
+			input:      "0138 status:conifer hills,west harbor,iron ore,coal,o ne,n,ford se,s,0138,0250e1",
+			settlement: "west harbor",
+			resources:  []string{"iron ore", "coal"},
+			units:      []string{"0138", "0250e1"},
+		},
+		{
+			name:       "settlement with commas inside parentheses",
+			input:      "0138 status:prairie,arcadia (port, market),lcm ne,n,sw s,0138",
+			settlement: "arcadia (port, market)",
+			neighbors: []tndocx.NeighboringTerrain{
+				{Terrain: "lcm", Directions: []string{"ne", "n"}},
+				{Terrain: "sw", Directions: []string{"s"}},
+			},
+			units: []string{"0138"},
+		},
+		{
+			name:       "quoted settlement",
+			input:      `0138 status:prairie,"dowdy holler",0138`,
+			settlement: "dowdy holler",
+			units:      []string{"0138"},
+		},
+		{
+			name:       "curly quoted settlement",
+			input:      "0138 status:prairie,\u201co'neal's ford\u201d,0138",
+			settlement: "o'neal's ford",
+			units:      []string{"0138"},
+		},
+		{
+			name:  "no settlement or resources",
+			input: "0138 status:prairie,o ne,grassy hills se,0138",
+			neighbors: []tndocx.NeighboringTerrain{
+				{Terrain: "gh", Directions: []string{"se"}},
+			},
+			units: []string{"0138"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tndocx.ToReport("test", [][]byte{
+				[]byte("tribe 0138,,current hex = ## 0709,(previous hex = ## 0709)"),
+				tndocx.CompressSpaces([]byte(tt.input)),
+			})
+			status := r.Units["0138"].Status
+			if status.Settlement != tt.settlement {
+				t.Errorf("Settlement = %q, want %q", status.Settlement, tt.settlement)
+			}
+			if !reflect.DeepEqual(status.Resources, tt.resources) {
+				t.Errorf("Resources = %q, want %q", status.Resources, tt.resources)
+			}
+			var neighbors []tndocx.NeighboringTerrain
+			for _, nt := range status.NeighboringTerrains {
+				neighbors = append(neighbors, *nt)
+			}
+			if !reflect.DeepEqual(neighbors, tt.neighbors) {
+				t.Errorf("NeighboringTerrains = %+v, want %+v", neighbors, tt.neighbors)
+			}
+			if !reflect.DeepEqual(status.Units, tt.units) {
+				t.Errorf("Units = %q, want %q", status.Units, tt.units)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+// UnitTimeline is the history of a single unit across a sequence of turns.
+type UnitTimeline struct {
+	UnitId string          `json:"unit-id"`
+	Turns  []*TimelineTurn `json:"turns,omitempty"`
+}
+
+// TimelineTurn is a unit's position and events for a single turn.
+// Reported is false when the unit only appears in the turn's events,
+// for example when it was disbanded before the report was generated.
+// From and To are empty when the unit was not reported.
+type TimelineTurn struct {
+	TurnId     string  `json:"turn-id"`
+	TurnNumber int     `json:"turn-number,omitempty"`
+	Reported   bool    `json:"reported,omitempty"`
+	From       string  `json:"from,omitempty"`
+	To         string  `json:"to,omitempty"`
+	Events     []Event `json:"events,omitempty"`
+}
+
+// BuildTimeline returns the timeline for every unit in the reports, keyed by unit id.
+// The reports should be for a single clan and in turn order; each turn is added
+// to the timelines in the order the reports are given.
+// A unit has an entry for each turn that it is reported in or is named in an event,
+// so units that are formed or disbanded part way through have gaps at either end.
+func BuildTimeline(reports []*Report) map[string]*UnitTimeline {
+	timelines := map[string]*UnitTimeline{}
+	for _, r := range reports {
+		turns := map[string]*TimelineTurn{}
+		entry := func(unitId string) *TimelineTurn {
+			if turn, ok := turns[unitId]; ok {
+				return turn
+			}
+			timeline, ok := timelines[unitId]
+			if !ok {
+				timeline = &UnitTimeline{UnitId: unitId}
+				timelines[unitId] = timeline
+			}
+			turn := &TimelineTurn{TurnId: r.TurnId, TurnNumber: r.TurnNumber}
+			timeline.Turns = append(timeline.Turns, turn)
+			turns[unitId] = turn
+			return turn
+		}
+		for _, id := range r.sortedUnitIds() {
+			unit := r.Units[id]
+			turn := entry(unit.Id)
+			turn.Reported, turn.From, turn.To = true, unit.From, unit.To
+		}
+		for _, event := range r.Events {
+			if event.UnitId != "" {
+				turn := entry(event.UnitId)
+				turn.Events = append(turn.Events, event)
+			}
+			if event.OtherUnitId != "" && event.OtherUnitId != event.UnitId {
+				turn := entry(event.OtherUnitId)
+				turn.Events = append(turn.Events, event)
+			}
+		}
+	}
+	return timelines
+}
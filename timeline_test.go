@@ -0,0 +1,64 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"github.com/playbymail/tndocx"
+	"testing"
+)
+
+func TestBuildTimeline(t *testing.T) {
+	reports := []*tndocx.Report{
+		toReport(
+			"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+			"Current Turn 900-04 (#4), Spring, FINE Next Turn 900-05 (#5), 14/02/2024",
+			"0138e1 Formed from 0138",
+		),
+		toReport(
+			"Tribe 0138, , Current Hex = QQ 0710, (Previous Hex = QQ 0709)",
+			"Current Turn 900-05 (#5), Spring, FINE Next Turn 900-06 (#6), 21/02/2024",
+			"Element 0138e1, , Current Hex = QQ 0810, (Previous Hex = QQ 0709)",
+		),
+		toReport(
+			"Tribe 0138, , Current Hex = QQ 0710, (Previous Hex = QQ 0710)",
+			"Current Turn 900-06 (#6), Spring, FINE Next Turn 900-07 (#7), 28/02/2024",
+			"0138e1 Disbanded into 0138",
+		),
+	}
+	timelines := tndocx.BuildTimeline(reports)
+	if len(timelines) != 2 {
+		t.Fatalf("len(timelines) = %d, want 2", len(timelines))
+	}
+
+	tribe := timelines["0138"]
+	if len(tribe.Turns) != 3 {
+		t.Fatalf("0138: len(Turns) = %d, want 3", len(tribe.Turns))
+	}
+	for i, want := range []struct{ turnId, from, to string }{
+		{"0900-04", "qq 0709", "qq 0709"},
+		{"0900-05", "qq 0709", "qq 0710"},
+		{"0900-06", "qq 0710", "qq 0710"},
+	} {
+		turn := tribe.Turns[i]
+		if turn.TurnId != want.turnId || !turn.Reported || turn.From != want.from || turn.To != want.to {
+			t.Errorf("0138: turn %d = %+v, want %s %s -> %s", i+1, turn, want.turnId, want.from, want.to)
+		}
+	}
+	if len(tribe.Turns[0].Events) != 1 || len(tribe.Turns[2].Events) != 1 {
+		t.Errorf("0138: events = %d, %d, want 1, 1", len(tribe.Turns[0].Events), len(tribe.Turns[2].Events))
+	}
+
+	element := timelines["0138e1"]
+	if len(element.Turns) != 3 {
+		t.Fatalf("0138e1: len(Turns) = %d, want 3", len(element.Turns))
+	}
+	if turn := element.Turns[0]; turn.Reported || turn.Events[0].Kind != tndocx.EventFormation {
+		t.Errorf("0138e1: turn 1 = %+v, want formation only", turn)
+	}
+	if turn := element.Turns[1]; !turn.Reported || turn.To != "qq 0810" {
+		t.Errorf("0138e1: turn 2 = %+v, want reported in qq 0810", turn)
+	}
+	if turn := element.Turns[2]; turn.Reported || turn.TurnId != "0900-06" || turn.Events[0].Kind != tndocx.EventDisband {
+		t.Errorf("0138e1: turn 3 = %+v, want disband only", turn)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// ExpectTurn returns an error if the turn id in the report doesn't match wantTurnId.
+// The input may be a Word document (.docx or legacy .doc) or plain text. Only the turn header is parsed,
+// so this is a cheap way for upload pipelines to reject last turn's report.
+// Turn ids are formatted as "YYYY-MM" (for example, "0900-04"). If the header only has
+// the turn number ("#4"), the number is compared with the number of wantTurnId.
+func ExpectTurn(input []byte, wantTurnId string) error {
+	if len(input) == 0 {
+		return ErrEmptyInput
+	}
+	if text, ok, err := readWordText(input, false); err != nil {
+		return err
+	} else if ok {
+		input = text
+	}
+	for len(input) != 0 {
+		line := input
+		if n := bytes.IndexByte(input, '\n'); n != -1 {
+			line, input = input[:n], input[n+1:]
+		} else {
+			input = nil
+		}
+		turnId, turnNumber, ok := parseTurnHeader(CompressSpaces(bytes.ToLower(bytes.TrimSpace(line))))
+		if !ok {
+			continue
+		} else if turnId == "" {
+			// older reports may only have the turn number
+			if wantTurnNumber := turnNumberFromId(wantTurnId); turnNumber != wantTurnNumber {
+				return fmt.Errorf("%w: got #%d, want #%d (%s)", ErrTurnMismatch, turnNumber, wantTurnNumber, wantTurnId)
+			}
+		} else if turnId != wantTurnId {
+			return fmt.Errorf("%w: got %q, want %q", ErrTurnMismatch, turnId, wantTurnId)
+		}
+		return nil
+	}
+	return ErrMissingTurnHeader
+}
+
+// parseTurnHeader returns the turn id and turn number from a turn header line.
+// Older reports may have only one of "900-04" and "(#4)", so each is returned
+// if found; the missing one is returned as the empty string or zero.
+// Returns false if the line is not a turn header.
+func parseTurnHeader(line []byte) (turnId string, turnNumber int, ok bool) {
+	match := rxTurnHeaderLine.FindSubmatch(line)
+	if match == nil {
+		return "", 0, false
+	}
+	if len(match[1]) != 0 {
+		year, _ := strconv.Atoi(string(match[1]))
+		month, _ := strconv.Atoi(string(match[2]))
+		turnId = fmt.Sprintf("%04d-%02d", year, month)
+	}
+	if len(match[3]) != 0 {
+		turnNumber, _ = strconv.Atoi(string(match[3]))
+	} else if len(match[4]) != 0 {
+		turnNumber, _ = strconv.Atoi(string(match[4]))
+	}
+	return turnId, turnNumber, turnId != "" || turnNumber != 0
+}
+
+// turnNumberFromId returns the turn number for a turn id.
+// The game starts in year 900, so turn "0900-04" is turn #4.
+func turnNumberFromId(turnId string) int {
+	var year, month int
+	if _, err := fmt.Sscanf(turnId, "%d-%d", &year, &month); err != nil {
+		return 0
+	}
+	return (year-900)*12 + month
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/playbymail/tndocx"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestExpectTurn(t *testing.T) {
+	input := []byte("Tribe 0138, , Current Hex = ## 0709, (Previous Hex = ## 0709)\nCurrent Turn 900-04 (#4), Summer, FINE\n")
+	doc, err := os.ReadFile("testdata/0900-04.0138.report.doc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		input    []byte
+		turnId   string
+		expected error
+	}{
+		{name: "matching turn", input: input, turnId: "0900-04"},
+		{name: "previous turn", input: input, turnId: "0900-05", expected: tndocx.ErrTurnMismatch},
+		{name: "no turn header", input: []byte("tribe 0138\n"), turnId: "0900-04", expected: tndocx.ErrMissingTurnHeader},
+		{name: "empty input", turnId: "0900-04", expected: tndocx.ErrEmptyInput},
+		{name: "turn number only", input: []byte("Current Turn #4, Summer, FINE\n"), turnId: "0900-04"},
+		{name: "turn number only, previous turn", input: []byte("Current Turn #4, Summer, FINE\n"), turnId: "0900-05", expected: tndocx.ErrTurnMismatch},
+		{name: "legacy word document", input: doc, turnId: "0900-04"},
+		{name: "legacy word document, previous turn", input: doc, turnId: "0900-05", expected: tndocx.ErrTurnMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tndocx.ExpectTurn(tt.input, tt.turnId)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("ExpectTurn() error = %v, want %v", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTurnHeaderForms(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		turnId     string
+		turnNumber int
+		season     string
+		weather    string
+		warnings   int
+	}{
+		{name: "both forms", input: "Current Turn 900-04 (#4), Summer, FINE", turnId: "0900-04", turnNumber: 4, season: "summer", weather: "fine"},
+		{name: "no season or weather", input: "Current Turn 900-04 (#4)", turnId: "0900-04", turnNumber: 4},
+		{name: "year and month only", input: "Current Turn 900-04, Summer, FINE", turnId: "0900-04", season: "summer", weather: "fine"},
+		{name: "turn number only", input: "Current Turn #4, Summer, FINE", turnNumber: 4, season: "summer", weather: "fine"},
+		{name: "turn number in parentheses only", input: "Current Turn (#16), Summer, FINE", turnNumber: 16, season: "summer", weather: "fine"},
+		{name: "conflicting forms", input: "Current Turn 900-05 (#4), Summer, FINE", turnId: "0900-05", turnNumber: 4, season: "summer", weather: "fine", warnings: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tndocx.IsTurnHeader(tndocx.CompressSpaces(bytes.ToLower([]byte(tt.input)))) {
+				t.Errorf("IsTurnHeader() = false, want true")
+			}
+			r := toReport("Tribe 0138, , Current Hex = ## 0709, (Previous Hex = ## 0709)", tt.input)
+			if r.TurnId != tt.turnId {
+				t.Errorf("TurnId = %q, want %q", r.TurnId, tt.turnId)
+			}
+			if r.TurnNumber != tt.turnNumber {
+				t.Errorf("TurnNumber = %d, want %d", r.TurnNumber, tt.turnNumber)
+			}
+			if r.Season != tt.season || r.Weather != tt.weather {
+				t.Errorf("Season, Weather = %q, %q, want %q, %q", r.Season, r.Weather, tt.season, tt.weather)
+			}
+			if len(r.Warnings) != tt.warnings {
+				t.Errorf("len(Warnings) = %d, want %d", len(r.Warnings), tt.warnings)
+			}
+		})
+	}
+}
+
+func TestWeatherEffects(t *testing.T) {
+	r := toReport(
+		"Current Turn 900-12 (#12), Winter, Snow",
+		"Snow, half movement",
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		"Mud: movement reduced",
+		"Tribe Movement: Move NE-PR",
+	)
+	if r.Season != "winter" || r.Weather != "snow" {
+		t.Errorf("Season, Weather = %q, %q, want %q, %q", r.Season, r.Weather, "winter", "snow")
+	}
+	want := []tndocx.WeatherEffect{
+		{Line: 2, Condition: "snow", Effect: "half movement"},
+		{Line: 4, UnitId: "0138", Condition: "mud", Effect: "movement reduced"},
+	}
+	if len(r.WeatherEffects) != len(want) {
+		t.Fatalf("WeatherEffects = %+v, want %+v", r.WeatherEffects, want)
+	}
+	for i := range want {
+		if r.WeatherEffects[i] != want[i] {
+			t.Errorf("WeatherEffects[%d] = %+v, want %+v", i, r.WeatherEffects[i], want[i])
+		}
+	}
+	if moves := r.Units["0138"].Moves; len(moves) != 1 {
+		t.Errorf("len(Moves) = %d, want 1", len(moves))
+	}
+}
+
+func TestTurnHeaderMidDocument(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"Current Turn 900-04 (#4), Summer, FINE",
+		"Tribe Movement: Move N-PR",
+		"Courier 0138c1, , Current Hex = QQ 0710, (Previous Hex = QQ 0709)",
+		"Current Turn 900-04 (#4), Summer, FINE",
+		"Tribe Movement: Move NE-GH\\SE-PR",
+		"Element 0138e1, , Current Hex = QQ 0711, (Previous Hex = QQ 0709)",
+		"Current Turn 900-05 (#5), Summer, FINE",
+		"Tribe Movement: Move S-SW",
+	)
+	if r.TurnId != "0900-04" || r.TurnNumber != 4 {
+		t.Errorf("TurnId, TurnNumber = %q, %d, want %q, %d", r.TurnId, r.TurnNumber, "0900-04", 4)
+	}
+	for id, want := range map[string]int{"0138": 1, "0138c1": 2, "0138e1": 1} {
+		if got := len(r.Units[id].Moves); got != want {
+			t.Errorf("%s: len(Moves) = %d, want %d", id, got, want)
+		}
+	}
+	if len(r.Warnings) != 1 || r.Warnings[0].Line != 8 || r.Warnings[0].UnitId != "0138e1" {
+		t.Errorf("Warnings = %+v, want one warning for line 8 of unit 0138e1", r.Warnings)
+	}
+}
+
+func TestTurnHeaderConflictingNumber(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"Current Turn 900-04 (#4), Summer, FINE",
+		"Courier 0138c1, , Current Hex = QQ 0710, (Previous Hex = QQ 0709)",
+		"Current Turn #5",
+	)
+	if r.TurnId != "0900-04" || r.TurnNumber != 4 {
+		t.Errorf("TurnId, TurnNumber = %q, %d, want %q, %d", r.TurnId, r.TurnNumber, "0900-04", 4)
+	}
+	want := []tndocx.Warning{{Line: 4, UnitId: "0138c1", Message: "turn #5 conflicts with turn #4"}}
+	if !reflect.DeepEqual(r.Warnings, want) {
+		t.Errorf("Warnings = %+v, want %+v", r.Warnings, want)
+	}
+}
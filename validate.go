@@ -0,0 +1,218 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// MissingFields returns, for each unit that is incomplete, the names of the expected fields that are absent.
+// The names match the JSON field names so players can find them in the output:
+//   - "name" if the unit header has no name
+//   - "to" if the current hex is missing, "n/a", or obscured ("## 0709")
+//   - "moves" if the unit has no movement
+//   - "status" if the unit has no status line
+//
+// Units with no missing fields are not included in the result.
+func (r *Report) MissingFields() map[string][]string {
+	missing := map[string][]string{}
+	for id, unit := range r.Units {
+		var fields []string
+		if unit.Name == "" {
+			fields = append(fields, "name")
+		}
+		if !isKnownHex(unit.To) || strings.HasPrefix(unit.To, "##") {
+			fields = append(fields, "to")
+		}
+		if len(unit.Moves) == 0 {
+			fields = append(fields, "moves")
+		}
+		if unit.Status == nil {
+			fields = append(fields, "status")
+		}
+		if len(fields) != 0 {
+			missing[id] = fields
+		}
+	}
+	return missing
+}
+
+// DanglingReferences returns a warning for each unit id that a unit refers to but
+// that doesn't have a header in the report. References come from follows orders,
+// the units found while moving or scouting, and the units listed on status lines.
+//
+// A reference to a unit from another clan is likely an enemy unit, which is expected.
+// A reference to a unit from the report's own clan is likely a typo in the id.
+// The warnings are informational and are sorted by the referring unit, then the reference.
+func (r *Report) DanglingReferences() []Warning {
+	refs := map[string]map[string]bool{}
+	add := func(unitId, ref string) {
+		if _, ok := r.Units[ref]; ok {
+			return
+		} else if refs[unitId] == nil {
+			refs[unitId] = map[string]bool{}
+		}
+		refs[unitId][ref] = true
+	}
+	for _, unit := range r.Units {
+		for _, step := range unit.Moves {
+			if step.Follows != "" {
+				add(unit.Id, step.Follows)
+			}
+		}
+	}
+	for ref, sightings := range r.Sightings {
+		for _, sighting := range sightings {
+			add(sighting.ObserverId, ref)
+		}
+	}
+
+	var warnings []Warning
+	for _, unitId := range sortedKeys(refs) {
+		for _, ref := range sortedKeys(refs[unitId]) {
+			message := "references " + ref + ": no unit header"
+			if len(r.ClanId) == 4 && len(ref) >= 4 {
+				if ref[1:4] == r.ClanId[1:4] {
+					message += ", likely a typo"
+				} else {
+					message += ", likely an enemy unit"
+				}
+			}
+			warnings = append(warnings, Warning{UnitId: unitId, Message: message})
+		}
+	}
+	return warnings
+}
+
+// Validate returns a warning for each unit whose recorded steps don't take it from its
+// previous hex to its current hex. A mismatch is usually an OCR error or a hand edit in
+// the unit header or the movement line, and would put the unit in the wrong hex on a map.
+//
+// The check is best-effort. Units are skipped when either hex is unknown, when the unit
+// follows another unit, or when the steps leave the grid, since moving between grids
+// isn't supported. The grid is the map size the report was parsed with, or the standard
+// map if the report doesn't record one. When either hex is obscured ("## 0709"), only the column and row are
+// compared. The warnings are sorted by unit id.
+func (r *Report) Validate() []Warning {
+	columns, rows := r.Meta.MapColumns, r.Meta.MapRows
+	if columns == 0 || rows == 0 {
+		columns, rows = DefaultMapColumns, DefaultMapRows
+	}
+	var warnings []Warning
+	for _, id := range r.sortedUnitIds() {
+		unit := r.Units[id]
+		from, ok := parseHexCoordinate(unit.From)
+		if !ok {
+			continue
+		}
+		to, ok := parseHexCoordinate(unit.To)
+		if !ok || slices.ContainsFunc(unit.Moves, func(step *Step) bool { return step.Kind == StepFollows }) {
+			continue
+		}
+		path := unit.Path(false)
+		if path == nil {
+			continue
+		}
+		end := path[len(path)-1]
+		if end.Column > columns || end.Row > rows {
+			continue
+		} else if end.Column == to.Column && end.Row == to.Row && (end.Grid == to.Grid || end.Obscured || to.Obscured) {
+			continue
+		}
+		warnings = append(warnings, Warning{
+			UnitId:  unit.Id,
+			Message: fmt.Sprintf("moves from %s end in %s, not current hex %s", from, end, to),
+		})
+	}
+	return warnings
+}
+
+// sortedKeys returns the keys of the map in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// HexInfo is the known-good information about a hex from a persistent map.
+// Terrain is the terrain code (for example, "pr").
+type HexInfo struct {
+	Terrain string  `json:"terrain,omitempty"`
+	Edges   []*Edge `json:"edges,omitempty"`
+}
+
+// Contradiction is a hex where the report disagrees with the known map.
+// Field is "terrain" or "edges".
+type Contradiction struct {
+	Hex      string `json:"hex"`
+	UnitId   string `json:"unit-id"`
+	Field    string `json:"field"`
+	Known    string `json:"known"`
+	Reported string `json:"reported"`
+}
+
+// ValidateAgainstMap returns the hexes where the report contradicts the known map.
+// The map is keyed by hex (for example, "QQ 0709"), in any spelling that CanonicalHex accepts. Each unit's status describes
+// its current hex, so the terrain and edges from the status are compared with the
+// map. Hexes that aren't in the map, and obscured hexes, are not checked. Edges are
+// only compared when both the map and the status have them, since a hex without
+// edges may just not have been observed.
+// Contradictions are returned in order of unit id.
+func ValidateAgainstMap(r *Report, known map[string]*HexInfo) []Contradiction {
+	// index the map by canonical hex so that spellings like "QQ 0709" and "qq 0709" match
+	canonical := make(map[string]*HexInfo, len(known))
+	for hex, info := range known {
+		canonical[canonicalHexOrInput(hex)] = info
+	}
+	var contradictions []Contradiction
+	for _, id := range r.sortedUnitIds() {
+		unit := r.Units[id]
+		if unit.Status == nil || !isKnownHex(unit.To) || strings.HasPrefix(unit.To, "##") {
+			continue
+		}
+		info, ok := canonical[canonicalHexOrInput(unit.To)]
+		if !ok {
+			continue
+		}
+		if info.Terrain != "" && unit.Status.TerrainCode != "" && info.Terrain != unit.Status.TerrainCode {
+			contradictions = append(contradictions, Contradiction{
+				Hex:      unit.To,
+				UnitId:   unit.Id,
+				Field:    "terrain",
+				Known:    info.Terrain,
+				Reported: unit.Status.TerrainCode,
+			})
+		}
+		if len(info.Edges) == 0 || len(unit.Status.Edges) == 0 {
+			continue
+		} else if knownEdges, reportedEdges := edgeSet(info.Edges), edgeSet(unit.Status.Edges); knownEdges != reportedEdges {
+			contradictions = append(contradictions, Contradiction{
+				Hex:      unit.To,
+				UnitId:   unit.Id,
+				Field:    "edges",
+				Known:    knownEdges,
+				Reported: reportedEdges,
+			})
+		}
+	}
+	return contradictions
+}
+
+// edgeSet returns the edges as a canonical string like "ocean ne,river n",
+// with one entry per direction, sorted so that edges can be compared.
+func edgeSet(edges []*Edge) string {
+	var set []string
+	for _, edge := range edges {
+		for _, direction := range edge.Directions {
+			set = append(set, edge.Type+" "+direction)
+		}
+	}
+	sort.Strings(set)
+	return strings.Join(set, ",")
+}
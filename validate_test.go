@@ -0,0 +1,125 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"github.com/playbymail/tndocx"
+	"reflect"
+	"testing"
+)
+
+func TestReportMissingFields(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, Dowdy Holler, Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"Tribe Movement: Move S-GH",
+		"0138 Status: GRASSY HILLS, 0138",
+		"Element 0138e1, , Current Hex = ## 0709, (Previous Hex = ## 0709)",
+		"Tribe Movement: Move",
+		"Courier 0138c1, Runner, Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		"Tribe Movement: Move N-PR",
+	)
+	want := map[string][]string{
+		"0138e1": {"name", "to", "moves", "status"},
+		"0138c1": {"status"},
+	}
+	if got := r.MissingFields(); !reflect.DeepEqual(got, want) {
+		t.Errorf("MissingFields() = %v, want %v", got, want)
+	}
+}
+
+func TestReportDanglingReferences(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"Scout 1:Scout N-GH, 0987e1\\Nothing of interest found",
+		"0138 Status: PRAIRIE, 0138, 0138e1, 0987",
+		"Element 0138e1, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		"Tribe Follows 0138e9",
+	)
+	want := []tndocx.Warning{
+		{UnitId: "0138", Message: "references 0987: no unit header, likely an enemy unit"},
+		{UnitId: "0138", Message: "references 0987e1: no unit header, likely an enemy unit"},
+		{UnitId: "0138e1", Message: "references 0138e9: no unit header, likely a typo"},
+	}
+	if got := r.DanglingReferences(); !reflect.DeepEqual(got, want) {
+		t.Errorf("DanglingReferences() = %+v, want %+v", got, want)
+	}
+}
+
+func TestValidateAgainstMap(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"0138 Status: OCEAN, O NE, 0138",
+		"Element 0138e1, , Current Hex = QQ 0710, (Previous Hex = QQ 0709)",
+		"0138e1 Status: PRAIRIE, O N, 0138e1",
+		"Courier 0138c1, , Current Hex = QQ 0101, (Previous Hex = QQ 0101)",
+		"0138c1 Status: PRAIRIE, 0138c1",
+	)
+	known := map[string]*tndocx.HexInfo{
+		"qq 0709": {Terrain: "pr", Edges: []*tndocx.Edge{{Type: "ocean", Directions: []string{"ne"}}}},
+		"qq 0710": {Terrain: "pr", Edges: []*tndocx.Edge{{Type: "ocean", Directions: []string{"n"}}}},
+	}
+	want := []tndocx.Contradiction{
+		{Hex: "qq 0709", UnitId: "0138", Field: "terrain", Known: "pr", Reported: "o"},
+	}
+	if got := tndocx.ValidateAgainstMap(r, known); !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidateAgainstMap() = %+v, want %+v", got, want)
+	}
+
+	// edges that weren't observed on either side aren't a contradiction
+	known["qq 0710"].Edges = nil
+	known["qq 0101"] = &tndocx.HexInfo{Terrain: "pr", Edges: []*tndocx.Edge{{Type: "river", Directions: []string{"s"}}}}
+	if got := tndocx.ValidateAgainstMap(r, known); !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidateAgainstMap() = %+v, want %+v", got, want)
+	}
+
+	known["qq 0710"].Edges = []*tndocx.Edge{{Type: "ocean", Directions: []string{"s"}}}
+	want = append(want, tndocx.Contradiction{Hex: "qq 0710", UnitId: "0138e1", Field: "edges", Known: "ocean s", Reported: "ocean n"})
+	if got := tndocx.ValidateAgainstMap(r, known); !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidateAgainstMap() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReportValidate(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"Tribe Movement: Move S-GH",
+		"Element 0138e1, , Current Hex = QQ 0712, (Previous Hex = QQ 0708)",
+		"Tribe Movement: Move S-GH\\SE-PR",
+		"Element 0138e2, , Current Hex = ## 0710, (Previous Hex = ## 0708)",
+		"Tribe Movement: Move S-GH\\S-PR",
+		"Element 0138e3, , Current Hex = QQ 0710, (Previous Hex = ## 0709)",
+		"Tribe Movement: Move S-GH",
+		"Element 0138e4, , Current Hex = RR 0709, (Previous Hex = QQ 0709)",
+		"Element 0138e5, , Current Hex = QQ 0709, (Previous Hex = N/A)",
+		"Tribe Movement: Move S-GH",
+		"Element 0138e6, , Current Hex = QQ 0712, (Previous Hex = QQ 0709)",
+		"Tribe Follows 0138",
+		"Element 0138e7, , Current Hex = QQ 0101, (Previous Hex = QQ 0101)",
+		"Tribe Movement: Move N-GH",
+	)
+	want := []tndocx.Warning{
+		{UnitId: "0138e1", Message: "moves from QQ 0708 end in QQ 0809, not current hex QQ 0712"},
+		{UnitId: "0138e4", Message: "moves from QQ 0709 end in QQ 0709, not current hex RR 0709"},
+	}
+	if got := r.Validate(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReportValidateMapSize(t *testing.T) {
+	input := [][]byte{
+		[]byte("tribe 0138,,current hex = qq 3212,(previous hex = qq 3010)"),
+		[]byte("tribe movement:move se-gh\\se-pr"),
+	}
+	if got := tndocx.ToReport("test", input).Validate(); got != nil {
+		t.Errorf("standard map: Validate() = %+v, want nil for moves that leave the grid", got)
+	}
+	p, err := tndocx.NewParser(tndocx.WithMapSize(40, 30))
+	if err != nil {
+		t.Fatalf("NewParser() error = %v", err)
+	}
+	want := []tndocx.Warning{{UnitId: "0138", Message: "moves from QQ 3010 end in QQ 3211, not current hex QQ 3212"}}
+	if got := p.ToReport("test", input).Validate(); !reflect.DeepEqual(got, want) {
+		t.Errorf("larger map: Validate() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Vocabulary is the set of terrains, resources, and edges that the parser recognizes.
+// Variants of the game may use a different vocabulary than the standard game.
+// The water terrains that fleets report as edges ("o N NE") are fixed to the
+// standard game's deep ocean, lake, ocean, and shallow ocean codes.
+type Vocabulary struct {
+	// Terrains maps the terrain code to the terrain name (for example, "gh" to "grassy hills").
+	Terrains map[string]string
+	// Resources is the set of resource names (for example, "iron ore").
+	Resources map[string]bool
+	// Edges is the set of edge names (for example, "stone road").
+	Edges map[string]bool
+	// Seasonal maps the name of a seasonal variant of a terrain or edge to the base
+	// name and the season it appears in (for example, "frozen lake" to "lake" in winter).
+	Seasonal map[string]SeasonalTerrain
+}
+
+// SeasonalTerrain is the base terrain or edge for a seasonal variant and the season it appears in.
+type SeasonalTerrain struct {
+	Base   string
+	Season string
+}
+
+// DefaultVocabulary returns the vocabulary for the standard TribeNet game.
+func DefaultVocabulary() Vocabulary {
+	v := Vocabulary{
+		Terrains: map[string]string{
+			"alps": "alps",
+			"ah":   "arid hills",
+			"ar":   "arid tundra",
+			"bf":   "brush flat",
+			"bh":   "brush hills",
+			"ch":   "conifer hills",
+			"d":    "deciduous",
+			"de":   "desert",
+			"dh":   "deciduous hills",
+			"do":   "deep ocean",
+			"gh":   "grassy hills",
+			"ghp":  "grassy hills plateau",
+			"hsm":  "high snowy mountains",
+			"jg":   "jungle",
+			"jh":   "jungle hills",
+			"l":    "lake",
+			"lam":  "low arid mountains",
+			"lcm":  "low conifer mountains",
+			"ljm":  "low jungle mountains",
+			"lsm":  "low snowy mountains",
+			"lvm":  "low volcanic mountains",
+			"o":    "ocean",
+			"pi":   "polar ice",
+			"pr":   "prairie",
+			"rh":   "rocky hills",
+			"sh":   "snowy hills",
+			"so":   "shallow ocean",
+			"sw":   "swamp",
+			"tu":   "tundra",
+		},
+		Resources: map[string]bool{},
+		Edges:     map[string]bool{},
+		Seasonal: map[string]SeasonalTerrain{
+			"dry riverbed": {Base: "river", Season: "summer"},
+			"frozen lake":  {Base: "lake", Season: "winter"},
+			"frozen river": {Base: "river", Season: "winter"},
+		},
+	}
+	for _, resource := range []string{"coal", "copper ore", "diamond", "frankincense", "gold", "iron ore", "jade", "kaolin", "lead ore", "limestone", "nickel ore", "pearls", "pyrite", "rubies", "salt", "silver ore", "sulphur", "tin ore", "vanadium ore", "zinc ore"} {
+		v.Resources[resource] = true
+	}
+	for _, edge := range []string{"canal", "ford", "pass", "river", "stone road"} {
+		v.Edges[edge] = true
+	}
+	return v
+}
+
+// TerrainCode returns the code for the terrain name.
+// Returns false if the name is not in the vocabulary.
+// If more than one code has the name, the lowest code is returned.
+func (v Vocabulary) TerrainCode(name string) (string, bool) {
+	found := ""
+	for code, terrain := range v.Terrains {
+		if terrain == name && (found == "" || code < found) {
+			found = code
+		}
+	}
+	return found, found != ""
+}
+
+// LoadVocabulary reads a vocabulary from a simple line-oriented text format.
+// Each line is a kind, followed by the entry:
+//
+//	terrain gh grassy hills
+//	resource iron ore
+//	edge stone road
+//	seasonal winter frozen lake = lake
+//
+// Blank lines and lines starting with "#" are ignored.
+// Entries are forced to lower case to match the parser's input.
+// Returns an error if a terrain name is used by more than one code.
+// The returned vocabulary contains only the entries from the reader.
+func LoadVocabulary(r io.Reader) (Vocabulary, error) {
+	v := Vocabulary{
+		Terrains:  map[string]string{},
+		Resources: map[string]bool{},
+		Edges:     map[string]bool{},
+		Seasonal:  map[string]SeasonalTerrain{},
+	}
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "terrain":
+			if len(fields) < 3 {
+				return Vocabulary{}, fmt.Errorf("%d: %w", lineNo, ErrMissingField)
+			}
+			code, name := fields[1], strings.Join(fields[2:], " ")
+			if other, ok := v.TerrainCode(name); ok && other != code {
+				return Vocabulary{}, fmt.Errorf("%d: %q: %w", lineNo, name, ErrDuplicateName)
+			}
+			v.Terrains[code] = name
+		case "resource":
+			if len(fields) < 2 {
+				return Vocabulary{}, fmt.Errorf("%d: %w", lineNo, ErrMissingField)
+			}
+			v.Resources[strings.Join(fields[1:], " ")] = true
+		case "edge":
+			if len(fields) < 2 {
+				return Vocabulary{}, fmt.Errorf("%d: %w", lineNo, ErrMissingField)
+			}
+			v.Edges[strings.Join(fields[1:], " ")] = true
+		case "seasonal":
+			// the shortest entry is "seasonal <season> <name> = <base>"
+			if len(fields) < 5 {
+				return Vocabulary{}, fmt.Errorf("%d: %w", lineNo, ErrMissingField)
+			}
+			name, base, ok := strings.Cut(strings.Join(fields[2:], " "), " = ")
+			if !ok || name == "" || base == "" {
+				return Vocabulary{}, fmt.Errorf("%d: %w", lineNo, ErrMissingField)
+			}
+			v.Seasonal[name] = SeasonalTerrain{Base: base, Season: fields[1]}
+		default:
+			return Vocabulary{}, fmt.Errorf("%d: %q: %w", lineNo, fields[0], ErrUnexpectedInput)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Vocabulary{}, err
+	}
+	return v, nil
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"errors"
+	"github.com/playbymail/tndocx"
+	"strings"
+	"testing"
+)
+
+func TestLoadVocabulary(t *testing.T) {
+	input := `# a variant with frozen tundra
+terrain ft Frozen Tundra
+terrain pr prairie
+resource mithril
+edge ice bridge
+`
+	v, err := tndocx.LoadVocabulary(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("LoadVocabulary() error = %v", err)
+	}
+	if got := v.Terrains["ft"]; got != "frozen tundra" {
+		t.Errorf("Terrains[ft] = %q, want %q", got, "frozen tundra")
+	}
+	if !v.Resources["mithril"] {
+		t.Errorf("Resources[mithril] = false, want true")
+	}
+	if !v.Edges["ice bridge"] {
+		t.Errorf("Edges[ice bridge] = false, want true")
+	}
+
+	lines := [][]byte{
+		[]byte("tribe 0138,,current hex = ## 0709,(previous hex = ## 0709)"),
+		[]byte("0138 status:frozen tundra,0138"),
+	}
+	tests := []struct {
+		name     string
+		options  []tndocx.Option
+		expected string
+	}{
+		{name: "default vocabulary", expected: ""},
+		{name: "custom vocabulary", options: []tndocx.Option{tndocx.WithVocabulary(v)}, expected: "ft"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := tndocx.NewParser(tt.options...)
+			if err != nil {
+				t.Fatalf("NewParser() error = %v", err)
+			}
+			status := p.ToReport("test", lines).Units["0138"].Status
+			if status.Terrain != "frozen tundra" {
+				t.Errorf("Terrain = %q, want %q", status.Terrain, "frozen tundra")
+			}
+			if status.TerrainCode != tt.expected {
+				t.Errorf("TerrainCode = %q, want %q", status.TerrainCode, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadVocabularyErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected error
+	}{
+		{name: "unknown kind", input: "mountain hsm\n", expected: tndocx.ErrUnexpectedInput},
+		{name: "terrain without name", input: "terrain ft\n", expected: tndocx.ErrMissingField},
+		{name: "terrain name reused", input: "terrain pr prairie\nterrain pa prairie\n", expected: tndocx.ErrDuplicateName},
+		{name: "seasonal without base", input: "seasonal winter frozen lake\n", expected: tndocx.ErrMissingField},
+		{name: "seasonal without season", input: "seasonal\n", expected: tndocx.ErrMissingField},
+		{name: "seasonal without name", input: "seasonal winter\n", expected: tndocx.ErrMissingField},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tndocx.LoadVocabulary(strings.NewReader(tt.input))
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("LoadVocabulary() error = %v, want %v", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadVocabularySeasonal(t *testing.T) {
+	input := `seasonal winter frozen tundra = tundra
+seasonal summer dry riverbed = river
+`
+	v, err := tndocx.LoadVocabulary(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("LoadVocabulary() error = %v", err)
+	}
+	for name, want := range map[string]tndocx.SeasonalTerrain{
+		"frozen tundra": {Base: "tundra", Season: "winter"},
+		"dry riverbed":  {Base: "river", Season: "summer"},
+	} {
+		if got := v.Seasonal[name]; got != want {
+			t.Errorf("Seasonal[%s] = %+v, want %+v", name, got, want)
+		}
+	}
+}
+
+func TestLoadVocabularyErrorLine(t *testing.T) {
+	_, err := tndocx.LoadVocabulary(strings.NewReader("terrain pr prairie\n\nseasonal\n"))
+	if !errors.Is(err, tndocx.ErrMissingField) || !strings.HasPrefix(err.Error(), "3: ") {
+		t.Errorf("LoadVocabulary() error = %v, want line 3: %v", err, tndocx.ErrMissingField)
+	}
+}
+
+func TestSeasonalTerrain(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0709)",
+		"Current Turn 900-12 (#12), Winter, Snow",
+		`Tribe Movement: Move N-PR, Dry Riverbed SE`,
+		"0138 Status: FROZEN LAKE, 0138",
+	)
+	unit := r.Units["0138"]
+	if unit.Status.Terrain != "lake" || unit.Status.TerrainCode != "l" || unit.Status.Seasonal != "frozen lake" {
+		t.Errorf("Status = %+v, want lake (l) reported as frozen lake", unit.Status)
+	}
+	edges := unit.Moves[0].Edges
+	if len(edges) != 1 || edges[0].Type != "river" || edges[0].Seasonal != "dry riverbed" {
+		t.Errorf("Edges = %+v, want river reported as dry riverbed", edges)
+	}
+	// a dry riverbed is a summer variant, so it's out of season in winter
+	if len(r.Warnings) != 1 || r.Warnings[0].Line != 3 {
+		t.Errorf("Warnings = %+v, want one warning for line 3", r.Warnings)
+	}
+}
+
+func TestVocabularyTerrainCodeDuplicate(t *testing.T) {
+	v := tndocx.Vocabulary{Terrains: map[string]string{"pr": "prairie", "pa": "prairie", "px": "prairie"}}
+	for n := 0; n < 10; n++ {
+		if code, ok := v.TerrainCode("prairie"); !ok || code != "pa" {
+			t.Fatalf("TerrainCode(prairie) = %q, %v, want %q, true", code, ok, "pa")
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"regexp"
+	"strings"
+)
+
+// WeatherEffect is a note that the weather changed how far units could move.
+// UnitId is empty when the note applies to the whole turn.
+type WeatherEffect struct {
+	Line      int    `json:"line,omitempty"`
+	UnitId    string `json:"unit-id,omitempty"`
+	Condition string `json:"condition"`
+	Effect    string `json:"effect"`
+}
+
+var (
+	// rxWeatherEffect captures the weather condition and its effect on movement.
+	// these look like:
+	// - snow,half movement
+	// - mud: movement reduced
+	// - heavy rain,movement costs doubled
+	rxWeatherEffect = regexp.MustCompile(`^(heavy rain|heavy snow|mud|rain|snow|storm)(?:,|:) ?(.*\bmovement\b.*)$`)
+)
+
+// weatherEffect returns the condition and effect from a weather effect note.
+// Returns false if the line is not a weather effect note.
+func weatherEffect(line []byte) (condition, effect string, ok bool) {
+	match := rxWeatherEffect.FindSubmatch(line)
+	if match == nil {
+		return "", "", false
+	}
+	return string(match[1]), strings.TrimSpace(string(match[2])), true
+}
+
+// seasonAndWeather returns the season and weather that follow the turn id
+// on a turn header, like "current turn 900-04(#4),summer,fine".
+// Missing values are returned as empty strings.
+func seasonAndWeather(line []byte) (season, weather string) {
+	loc := rxTurnHeaderLine.FindIndex(line)
+	if loc == nil {
+		return "", ""
+	}
+	fields := strings.Split(strings.TrimLeft(string(line[loc[1]:]), ", "), ",")
+	if len(fields) > 0 {
+		season = strings.TrimSpace(fields[0])
+	}
+	if len(fields) > 1 {
+		weather = strings.TrimSpace(fields[1])
+	}
+	return season, weather
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// WriteReportsNDJSON writes each report as a single line of JSON, which is the
+// newline-delimited JSON format that jq and most bulk loaders read. Each line is
+// a complete report, including its file name, so it can be parsed on its own.
+// Write one report at a time to stream the reports as they are parsed.
+func WriteReportsNDJSON(w io.Writer, reports []*Report) error {
+	enc := json.NewEncoder(w)
+	for _, r := range reports {
+		// the encoder ends each value with a newline
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteTable writes the units in the report as aligned columns for reading in a terminal.
+// Units are written in order of their id.
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	if _, err := fmt.Fprintln(tw, "ID\tKIND\tFROM\tTO\tTERRAIN\tSTATUS"); err != nil {
+		return err
+	}
+	for _, id := range r.sortedUnitIds() {
+		unit := r.Units[id]
+		var terrain, status string
+		if unit.Status != nil {
+			terrain, status = unit.Status.Terrain, unit.Status.Raw
+		}
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", unit.Id, unit.Kind, unit.From, unit.To, terrain, status); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tndocx_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/playbymail/tndocx"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReportWriteTable(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"0138 Status: PRAIRIE, 0138",
+		"Element 0138e1, , Current Hex = QQ 0710, (Previous Hex = QQ 0709)",
+		"0138e1 Status: GRASSY HILLS, 0138e1",
+	)
+	buf := &bytes.Buffer{}
+	if err := r.WriteTable(buf); err != nil {
+		t.Fatalf("WriteTable() error = %v", err)
+	}
+	want := []string{
+		"ID      KIND     FROM     TO       TERRAIN       STATUS",
+		"0138    tribe    qq 0708  qq 0709  prairie       prairie,0138",
+		"0138e1  element  qq 0709  qq 0710  grassy hills  grassy hills,0138e1",
+	}
+	got := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("WriteTable() wrote %d lines, want %d:\n%s", len(got), len(want), buf.String())
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d: got %q, want %q", i+1, got[i], want[i])
+		}
+	}
+}
+
+func TestReportJSONRoundTrip(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"Current Turn 900-04 (#4), Summer, FINE",
+		`Tribe Movement: Move S-PR/GH,  L NE,  River SE S\No Ford on River to SE of HEX`,
+		"Scout 1:Scout N-GH,  Nothing of interest found",
+		"0138 Status: PRAIRIE, O NE, 0138",
+		"Courier 0138c1, , Current Hex = QQ 0710, (Previous Hex = QQ 0709)",
+	)
+	buf := &bytes.Buffer{}
+	if err := r.WriteJSON(buf); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	got, err := tndocx.ReadReportJSON(buf)
+	if err != nil {
+		t.Fatalf("ReadReportJSON() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, r) {
+		t.Errorf("ReadReportJSON() = %+v, want %+v", got, r)
+	}
+}
+
+func TestReportEncodeDecode(t *testing.T) {
+	r := toReport(
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"Current Turn 900-04 (#4), Summer, FINE",
+		`Tribe Movement: Move S-PR/GH,  L NE,  River SE S\No Ford on River to SE of HEX`,
+		"0138 Status: PRAIRIE, O NE, 0138",
+	)
+	data, err := r.Encode()
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Contains(data, []byte(`"schema-version":1`)) {
+		t.Errorf("Encode() = %s, want schema-version 1", data)
+	}
+	got, err := tndocx.DecodeReport(data)
+	if err != nil {
+		t.Fatalf("DecodeReport() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, r) {
+		t.Errorf("DecodeReport() = %+v, want %+v", got, r)
+	}
+	again, err := got.Encode()
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(again, data) {
+		t.Errorf("Encode() is not stable:\n%s\n%s", data, again)
+	}
+
+	for _, tt := range []struct {
+		name, input string
+		err         error
+	}{
+		{name: "other generator", input: `{"metadata":{"generated-by":"tn2","schema-version":1}}`, err: tndocx.ErrUnknownFormat},
+		{name: "no generator", input: `{"units":{}}`, err: tndocx.ErrUnknownFormat},
+		{name: "newer schema", input: `{"metadata":{"generated-by":"tn3","schema-version":2}}`, err: tndocx.ErrUnsupportedSchema},
+	} {
+		if _, err := tndocx.DecodeReport([]byte(tt.input)); !errors.Is(err, tt.err) {
+			t.Errorf("%s: DecodeReport() error = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestReportConsistencyHash(t *testing.T) {
+	lines := []string{
+		"Tribe 0138, , Current Hex = QQ 0709, (Previous Hex = QQ 0708)",
+		"Current Turn 900-04 (#4), Summer, FINE",
+		`Tribe Movement: Move N-PR\NE-GH`,
+		"0138 Status: PRAIRIE, O NE, 0138",
+		"Courier 0138c1, , Current Hex = QQ 0710, (Previous Hex = QQ 0709)",
+	}
+	first, second := toReport(lines...), toReport(lines...)
+	second.FileName, second.Meta.Timestamp = "renamed", first.Meta.Timestamp+60
+	second.Meta.AuthoringTool, second.Meta.GeneratedDate = "word", "2024-06-01T12:00:00Z"
+	second.Meta.Legend = map[string]string{"pr": "prairie"}
+	if consistencyHash(t, first) != consistencyHash(t, second) {
+		t.Errorf("ConsistencyHash() differs between parses of the same input")
+	}
+	second.Units["0138c1"].To = "qq 0711"
+	if consistencyHash(t, first) == consistencyHash(t, second) {
+		t.Errorf("ConsistencyHash() did not change when a unit changed")
+	}
+}
+
+func consistencyHash(t *testing.T, r *tndocx.Report) string {
+	t.Helper()
+	hash, err := r.ConsistencyHash()
+	if err != nil {
+		t.Fatalf("ConsistencyHash() error = %v", err)
+	}
+	return hash
+}
+
+func TestWriteReportsNDJSON(t *testing.T) {
+	var reports []*tndocx.Report
+	for _, name := range []string{"0900-04.0138.report.txt", "0900-05.0138.report.txt"} {
+		reports = append(reports, tndocx.ToReport(name, [][]byte{
+			[]byte("tribe 0138,,current hex = qq 0709,(previous hex = qq 0708)"),
+			[]byte("0138 status:prairie,0138"),
+		}))
+	}
+	buf := &bytes.Buffer{}
+	if err := tndocx.WriteReportsNDJSON(buf, reports); err != nil {
+		t.Fatalf("WriteReportsNDJSON() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(reports) {
+		t.Fatalf("WriteReportsNDJSON() wrote %d lines, want %d:\n%s", len(lines), len(reports), buf.String())
+	}
+	for n, line := range lines {
+		got, err := tndocx.DecodeReport([]byte(line))
+		if err != nil {
+			t.Fatalf("line %d: DecodeReport() error = %v", n+1, err)
+		}
+		if got.FileName != reports[n].FileName {
+			t.Errorf("line %d: file name = %q, want %q", n+1, got.FileName, reports[n].FileName)
+		}
+		if !reflect.DeepEqual(got.Units, reports[n].Units) {
+			t.Errorf("line %d: units = %+v, want %+v", n+1, got.Units, reports[n].Units)
+		}
+	}
+}